@@ -2,19 +2,307 @@ package api
 
 import (
 	"api_gateway/api/handler"
+	"api_gateway/api/health"
 	"api_gateway/api/middleware"
+	"api_gateway/api/openapi"
+	"api_gateway/api/response"
 	"api_gateway/config"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func NewRouter(cfg *config.Config) *gin.Engine {
-	r := gin.Default()
-	api := r.Group("/listenup")
-	api.Use(middleware.JWTMiddleware())
+// The API is served under a group per version. The unversioned paths predate
+// versioning and stay as aliases of v1.
+const (
+	apiPrefix = "/listenup"
+	v1Group   = "/v1"
+	v2Group   = "/v2"
+)
+
+var apiPrefixes = []string{apiPrefix, apiPrefix + v1Group, apiPrefix + v2Group}
+
+// uploadRoutes carry episode audio and get their own rate and body limits.
+var uploadRoutes = versioned(
+	"/podcasts/:id/episodes",
+	"/podcasts/:id/episodes/:episodeid",
+)
+
+// strictRoutes create or replace resources and reject JSON bodies with
+// fields the backend would silently drop.
+var strictRoutes = versioned(
+	"/users/:id",
+	"/users/:id/profile",
+	"/podcasts/",
+	"/podcasts/:id",
+	"/collaborations/invite",
+	"/collaborations/invite/:id/respond",
+	"/podcasts/:id/collaborators/:userid",
+	"/podcasts/:id/comments",
+)
+
+// coalescedRoutes are hot reads whose identical concurrent requests share
+// one backend call: across callers for public backend methods, otherwise
+// only for the same caller.
+var coalescedRoutes = versioned(
+	"/podcasts/:id",
+	"/podcasts/:id/episodes",
+	"/podcasts/:id/collaborators",
+	"/podcasts/:id/comments",
+	"/discover/trending",
+	"/discover/genres",
+)
+
+// idempotentRoutes create resources and accept an Idempotency-Key, so
+// clients can retry them without creating duplicates.
+var idempotentRoutes = versionedMethods(
+	"POST /podcasts/",
+	"POST /podcasts/:id/episodes",
+	"POST /collaborations/invite",
+	"POST /podcasts/:id/comments",
+)
+
+// discoveryTag marks cached discovery results, which change when podcasts
+// or episodes do.
+const discoveryTag = "discovery"
+
+// discoveryMutations invalidate the cached discovery results on success.
+var discoveryMutations = versionedMethods(
+	"POST /podcasts/",
+	"PUT /podcasts/:id",
+	"DELETE /podcasts/:id",
+	"POST /podcasts/:id/publish",
+	"POST /podcasts/:id/episodes",
+	"PUT /podcasts/:id/episodes/:episodeid",
+	"DELETE /podcasts/:id/episodes/:episodeid",
+)
+
+// versioned expands routes relative to a version group into the full paths
+// of every version.
+func versioned(routes ...string) []string {
+	full := make([]string, 0, len(routes)*len(apiPrefixes))
+	for _, prefix := range apiPrefixes {
+		for _, route := range routes {
+			full = append(full, prefix+route)
+		}
+	}
+	return full
+}
+
+// versionedMethods is versioned for routes written as "METHOD route".
+func versionedMethods(routes ...string) []string {
+	full := make([]string, 0, len(routes)*len(apiPrefixes))
+	for _, prefix := range apiPrefixes {
+		for _, route := range routes {
+			method, path, _ := strings.Cut(route, " ")
+			full = append(full, method+" "+prefix+path)
+		}
+	}
+	return full
+}
+
+// noProtobufRoutes lists the documented routes whose success body is not a
+// proto message and so cannot be sent as protobuf.
+func noProtobufRoutes(operations map[string]openapi.Operation) []string {
+	routes := []string{}
+	for key, op := range operations {
+		if op.Response == nil && op.ContentType != "" {
+			routes = append(routes, key)
+		}
+	}
+	return routes
+}
+
+// cacheRules caches discovery results, per user where they are
+// personalized.
+func cacheRules(cfg *config.Config) middleware.CacheRules {
+	rules := middleware.CacheRules{
+		Routes:        map[string]middleware.CachePolicy{},
+		Invalidations: map[string][]string{},
+	}
+	policies := map[string]middleware.CachePolicy{
+		"/discover/trending":            {TTL: cfg.CACHE_TTL_TRENDING},
+		"/discover/genres":              {TTL: cfg.CACHE_TTL_GENRES},
+		"/discover/recommended/:userid": {TTL: cfg.CACHE_TTL_RECOMMENDED, PerUser: true},
+	}
+	for route, policy := range policies {
+		policy.Tags = []string{discoveryTag}
+		for _, full := range versioned(route) {
+			rules.Routes[full] = policy
+		}
+	}
+	for _, mutation := range discoveryMutations {
+		rules.Invalidations[mutation] = []string{discoveryTag}
+	}
+	return rules
+}
+
+// NewRouter returns the gateway's router: the API, the probes and metrics,
+// and the OpenAPI document describing them with Swagger UI at /docs.
+func NewRouter(cfg *config.Config, probe *health.Probe) *gin.Engine {
+	r := apiRouter(cfg, probe)
+
+	spec, err := buildSpec(cfg, r.Routes())
+	if err != nil {
+		slog.Error("OpenAPI document does not match the routes", "error", err)
+	}
+	r.GET("/openapi.json", spec.Handler())
+	r.GET("/docs", spec.UI("/openapi.json", "/docs"))
+	r.GET("/docs/:asset", openapi.UIAsset)
+
+	return r
+}
+
+// buildSpec documents routes, failing when the operations do not match
+// them.
+func buildSpec(cfg *config.Config, routes gin.RoutesInfo) (*openapi.Document, error) {
+	return openapi.Build(openapi.Info{
+		Title:   "ListenUp API Gateway",
+		Version: "1.0.0",
+	}, routes, documentedOperations(cfg), response.MarshalOptions())
+}
+
+// apiRouter registers every route the OpenAPI document describes.
+func apiRouter(cfg *config.Config, probe *health.Probe) *gin.Engine {
+	r := gin.New()
+	r.ContextWithFallback = true
+	if err := r.SetTrustedProxies(cfg.TRUSTED_PROXIES); err != nil {
+		slog.Error("invalid trusted proxies, trusting none", "error", err)
+		r.SetTrustedProxies(nil)
+	}
+	r.Use(
+		middleware.AccessLog(slog.Default(), cfg.LOG_SUCCESS_SAMPLE_RATE),
+		middleware.Tracing(cfg.SERVICE_NAME),
+		middleware.RequestID(),
+		middleware.Metrics(),
+		middleware.Recovery(),
+		middleware.CORS(middleware.CORSConfig{
+			AllowedOrigins:   cfg.CORS_ALLOWED_ORIGINS,
+			AllowedMethods:   cfg.CORS_ALLOWED_METHODS,
+			AllowedHeaders:   cfg.CORS_ALLOWED_HEADERS,
+			ExposedHeaders:   cfg.CORS_EXPOSED_HEADERS,
+			AllowCredentials: cfg.CORS_ALLOW_CREDENTIALS,
+			MaxAge:           cfg.CORS_MAX_AGE,
+		}),
+		middleware.Compress(middleware.CompressionConfig{
+			MinSize:     cfg.COMPRESSION_MIN_SIZE,
+			GzipLevel:   cfg.COMPRESSION_GZIP_LEVEL,
+			BrotliLevel: cfg.COMPRESSION_BROTLI_LEVEL,
+		}),
+	)
+	r.NoRoute(func(ctx *gin.Context) {
+		response.Abort(ctx, http.StatusNotFound, "no route for "+ctx.Request.Method+" "+ctx.Request.URL.Path)
+	})
+	r.GET("/healthz", probe.Live)
+	r.GET("/readyz", probe.Ready)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Every version shares the same middleware instances, so a client's
+	// rate limit is counted across versions. The IP limit comes before
+	// authentication so that requests failing it are counted too.
+	buckets := middleware.NewMemoryStore(cfg.RATE_LIMIT_SHARDS, time.Hour)
+	shared := []gin.HandlerFunc{
+		middleware.RateLimitIP(buckets, middleware.Limit{Rate: cfg.RATE_LIMIT_IP_RPS, Burst: cfg.RATE_LIMIT_IP_BURST}),
+		middleware.JWTMiddleware(),
+		middleware.RateLimit(
+			buckets,
+			middleware.RateLimits{
+				Reads:        middleware.Limit{Rate: cfg.RATE_LIMIT_READ_RPS, Burst: cfg.RATE_LIMIT_READ_BURST},
+				Writes:       middleware.Limit{Rate: cfg.RATE_LIMIT_WRITE_RPS, Burst: cfg.RATE_LIMIT_WRITE_BURST},
+				Uploads:      middleware.Limit{Rate: cfg.RATE_LIMIT_UPLOAD_RPS, Burst: cfg.RATE_LIMIT_UPLOAD_BURST},
+				UploadRoutes: uploadRoutes,
+			},
+		),
+		middleware.BodyLimit(middleware.BodyLimits{
+			Default:       cfg.BODY_LIMIT_DEFAULT,
+			Upload:        cfg.BODY_LIMIT_UPLOAD,
+			UploadRoutes:  uploadRoutes,
+			StrictRoutes:  strictRoutes,
+			ContentTypes:  cfg.BODY_CONTENT_TYPES,
+			ReadTimeout:   cfg.HTTP_READ_TIMEOUT,
+			UploadTimeout: cfg.HTTP_UPLOAD_TIMEOUT,
+		}),
+		middleware.Negotiate(noProtobufRoutes(documentedOperations(cfg))),
+		middleware.Idempotency(middleware.NewMemoryIdempotencyStore(cfg.IDEMPOTENCY_MAX_BYTES, time.Minute), cfg.IDEMPOTENCY_TTL, idempotentRoutes),
+		middleware.Cache(middleware.NewLRUCache(cfg.CACHE_MAX_BYTES), cacheRules(cfg)),
+		middleware.Coalesce(coalescedRoutes),
+	}
 
 	h := handler.NewHandler(cfg)
 
+	legacy := r.Group(apiPrefix)
+	if !cfg.API_LEGACY_DEPRECATED_AT.IsZero() {
+		legacy.Use(middleware.Deprecated(middleware.Deprecation{
+			Since:     cfg.API_LEGACY_DEPRECATED_AT,
+			Sunset:    cfg.API_LEGACY_SUNSET,
+			Prefix:    apiPrefix,
+			Successor: apiPrefix + v1Group,
+		}))
+	}
+	v1Routes(legacy.Group("", shared...), h)
+
+	v1 := r.Group(apiPrefix + v1Group)
+	if !cfg.API_V1_DEPRECATED_AT.IsZero() {
+		v1.Use(middleware.Deprecated(middleware.Deprecation{
+			Since:     cfg.API_V1_DEPRECATED_AT,
+			Sunset:    cfg.API_V1_SUNSET,
+			Prefix:    apiPrefix + v1Group,
+			Successor: apiPrefix + v2Group,
+		}))
+	}
+	v1Routes(v1.Group("", shared...), h)
+
+	v2Routes(r.Group(apiPrefix+v2Group, shared...), h)
+
+	return r
+}
+
+// v1Routes registers the contract frozen as v1.
+func v1Routes(api *gin.RouterGroup, h *handler.Handler) {
+	api.Use(numericInt64)
+	resourceRoutes(api, h)
+
+	api.GET("/users/:id/podcasts", h.GetUserPodcasts)
+	api.GET("/podcasts/:id/episodes", h.GetEpisodesByPodcastId)
+	api.GET("/podcasts/:id/comments", h.GetCommentsByPodcastId)
+
+	discover := api.Group("/discover")
+	discover.GET("/trending", h.GetTrendingPodcasts)
+	discover.GET("/recommended/:userid", h.GetRecommendedPodcasts)
+	discover.GET("/genres", h.GetPodcastsByGenre)
+	api.GET("/search", h.SearchPodcast)
+}
+
+// numericInt64 keeps the 64-bit integers of v1 responses JSON numbers, as
+// they were before proto messages were rendered with protojson.
+func numericInt64(ctx *gin.Context) {
+	ctx.Set(response.NumericInt64Key, true)
+}
+
+// v2Routes registers v2, which differs from v1 in lists being returned in
+// the paginated envelope, search taking its title from the query string and
+// 64-bit integers being rendered as strings, as protojson writes them.
+func v2Routes(api *gin.RouterGroup, h *handler.Handler) {
+	resourceRoutes(api, h)
+
+	api.GET("/users/:id/podcasts", h.GetUserPodcastsV2)
+	api.GET("/podcasts/:id/episodes", h.GetEpisodesByPodcastIdV2)
+	api.GET("/podcasts/:id/comments", h.GetCommentsByPodcastIdV2)
+
+	discover := api.Group("/discover")
+	discover.GET("/trending", h.GetTrendingPodcastsV2)
+	discover.GET("/recommended/:userid", h.GetRecommendedPodcastsV2)
+	discover.GET("/genres", h.GetPodcastsByGenreV2)
+	api.GET("/search", h.SearchPodcastV2)
+}
+
+// resourceRoutes registers the routes whose contract is the same in every
+// version.
+func resourceRoutes(api *gin.RouterGroup, h *handler.Handler) {
 	users := api.Group("/users")
 	users.GET("/:id", h.GetUserByID)
 	users.PUT("/:id", h.UpdateUser)
@@ -27,9 +315,8 @@ func NewRouter(cfg *config.Config) *gin.Engine {
 	podcasts.GET("/:id", h.GetPodcastById)
 	podcasts.PUT("/:id", h.UpdatePodcast)
 	podcasts.DELETE("/:id", h.DeletePodcast)
-	users.GET("/:id/podcasts", h.GetUserPodcasts)
 	podcasts.POST("/:id/episodes", h.CreatePodcastEpisode)
-	podcasts.GET("/:id/episodes", h.GetEpisodesByPodcastId)
+	podcasts.GET("/:id/episodes/:episodeid", h.GetEpisode)
 	podcasts.PUT("/:id/episodes/:episodeid", h.UpdateEpisode)
 	podcasts.DELETE("/:id/episodes/:episodeid", h.DeleteEpisode)
 	podcasts.POST("/:id/publish", h.PublishPodcast)
@@ -41,16 +328,8 @@ func NewRouter(cfg *config.Config) *gin.Engine {
 	podcasts.PUT("/:id/collaborators/:userid", h.UpdateCollaboratorByPodcastId)
 	podcasts.DELETE("/:id/collaborators/:userid", h.DeleteCollaboratorByPodcastId)
 	podcasts.POST("/:id/comments", h.CreateCommentByPodcastId)
-	podcasts.GET("/:id/comments", h.GetCommentsByPodcastId)
 
-	discover := api.Group("/discover")
-	discover.GET("/trending", h.GetTrendingPodcasts)
-	discover.GET("/recommended/:userid", h.GetRecommendedPodcasts)
-	discover.GET("/genres", h.GetPodcastsByGenre)
-	api.GET("/search", h.SearchPodcast)
 	podcasts.POST("/:id/like", h.LikeEpisodeOfPodcast)
 	podcasts.DELETE("/:id/like", h.DeleteLikeFromEpisodeOfPodcast)
 	podcasts.POST("/:id/listen", h.ListenEpisodeOfPodcast)
-
-	return r
 }
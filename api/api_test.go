@@ -0,0 +1,62 @@
+package api
+
+import (
+	"api_gateway/api/health"
+	"api_gateway/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testConfig loads the configuration defaults from an empty .env file.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	return config.Load()
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	r := apiRouter(cfg, health.NewProbe(time.Second, time.Second))
+
+	if _, err := buildSpec(cfg, r.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRateLimitBeforeAuth checks that requests failing authentication are
+// counted against the limit of their IP.
+func TestRateLimitBeforeAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := testConfig(t)
+	cfg.RATE_LIMIT_IP_RPS = 0.001
+	cfg.RATE_LIMIT_IP_BURST = 3
+	r := apiRouter(cfg, health.NewProbe(time.Second, time.Second))
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, apiPrefix+"/podcasts/42", nil)
+		req.Header.Set("Authorization", "Bearer not-a-token")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("request %d: status %d, want %d", i+1, w.Code, want)
+		}
+	}
+}
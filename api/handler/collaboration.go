@@ -1,11 +1,10 @@
 package handler
 
 import (
+	"api_gateway/api/response"
 	pb "api_gateway/genproto/collaborations"
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -16,13 +15,7 @@ import (
 
 func (h *Handler) SendInvitation(ctx *gin.Context) {
 	invitation := pb.CreateInvite{}
-	err := json.NewDecoder(ctx.Request.Body).Decode(&invitation)
-	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "Error while decoding",
-		})
-		log.Println("Error while decoding")
+	if !bind(ctx, &invitation) {
 		return
 	}
 
@@ -31,56 +24,40 @@ func (h *Handler) SendInvitation(ctx *gin.Context) {
 
 	id, err := h.ClientCollaboration.CreateInvitation(tctx, &invitation)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusInternalServerError, gin.H{
-			"Error":   err.Error(),
-			"Message": "Error while creating invitation",
-		})
-		log.Println("Error while creating invitation ", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error while creating invitation", "error", err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, id)
+	response.JSON(ctx, http.StatusCreated, id)
 }
 
 func (h *Handler) RepondInvitation(ctx *gin.Context) {
 	collaboration := pb.CreateCollaboration{}
-	err := json.NewDecoder(ctx.Request.Body).Decode(&collaboration)
-	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "Error while decoding",
-		})
-		log.Println("Error while decoding ", err)
+	if !bind(ctx, &collaboration) {
 		return
 	}
 
 	id := ctx.Param("id")
 
-	_, err = uuid.Parse(id)
+	_, err := uuid.Parse(id)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "no id or invalid uuid",
-		})
-		log.Println("no id or invalid uuid ", err)
+		response.Abort(ctx, http.StatusBadRequest, "no id or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no id or invalid uuid", "error", err)
 		return
 	}
 	collaboration.InvitationId = id
-	fmt.Println(collaboration)
 	tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	collabId, err := h.ClientCollaboration.RespondInvitation(tctx, &collaboration)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "Error while responding and creating collaboration ",
-		})
-		log.Println("Error while responding and creating collaboration ", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error while responding and creating collaboration", "error", err)
 		return
 	}
 
-	ctx.IndentedJSON(http.StatusCreated, gin.H{
+	response.JSON(ctx, http.StatusCreated, gin.H{
 		"Id": collabId,
 	})
 }
@@ -91,11 +68,8 @@ func (h *Handler) GetCollaboratorsByPodcastId(ctx *gin.Context) {
 
 	_, err := uuid.Parse(podcastId)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err,
-			"Message": "no id or invalid uuid",
-		})
-		log.Println("no id or invalid uuid ", err)
+		response.Abort(ctx, http.StatusBadRequest, "no id or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no id or invalid uuid", "error", err)
 		return
 	}
 
@@ -104,49 +78,34 @@ func (h *Handler) GetCollaboratorsByPodcastId(ctx *gin.Context) {
 
 	collaborators, err := h.ClientCollaboration.GetCollaboratorsByPodcastId(tctx, &pb.ID{Id: podcastId})
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "Error while getting collaborators by podcast_id",
-		})
-		log.Println("Error while getting collaborators by podcast_id ", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error while getting collaborators by podcast_id", "error", err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, collaborators)
+	response.ConditionalJSON(ctx, http.StatusOK, collaborators)
 }
 
 func (h *Handler) UpdateCollaboratorByPodcastId(ctx *gin.Context) {
 
 	req := &pb.UpdateCollaborator{}
-	err := json.NewDecoder(ctx.Request.Body).Decode(&req)
-	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "Error while decoding",
-		})
-		log.Println("Error while decoding ", err)
+	if !bind(ctx, req) {
 		return
 	}
 
 	podcastId := ctx.Param("id")
-	_, err = uuid.Parse(podcastId)
+	_, err := uuid.Parse(podcastId)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "no id or invalid uuid",
-		})
-		log.Println("no id or invalid uuid ", err)
+		response.Abort(ctx, http.StatusBadRequest, "no id or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no id or invalid uuid", "error", err)
 		return
 	}
 
 	userId := ctx.Param("userid")
 	_, err = uuid.Parse(userId)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "no userId or invalid uuid",
-		})
-		log.Println("no userId or invalid uuid ", err)
+		response.Abort(ctx, http.StatusBadRequest, "no userId or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no userId or invalid uuid", "error", err)
 		return
 	}
 
@@ -158,11 +117,8 @@ func (h *Handler) UpdateCollaboratorByPodcastId(ctx *gin.Context) {
 
 	_, err = h.ClientCollaboration.UpdateCollaboratorByPodcastId(tctx, req)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "error while updating collaborator by podcastId",
-		})
-		log.Println("error while updating collaborator by podcastId ", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "error while updating collaborator by podcastId", "error", err)
 		return
 	}
 
@@ -174,22 +130,16 @@ func (h *Handler) DeleteCollaboratorByPodcastId(ctx *gin.Context) {
 	podcastId := ctx.Param("id")
 	_, err := uuid.Parse(podcastId)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "no id or invalid uuid",
-		})
-		log.Println("no id or invalid uuid ", err)
+		response.Abort(ctx, http.StatusBadRequest, "no id or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no id or invalid uuid", "error", err)
 		return
 	}
 
 	userId := ctx.Param("userid")
 	_, err = uuid.Parse(userId)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "no userId or invalid uuid",
-		})
-		log.Println("no userId or invalid uuid ", err)
+		response.Abort(ctx, http.StatusBadRequest, "no userId or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no userId or invalid uuid", "error", err)
 		return
 	}
 
@@ -201,11 +151,8 @@ func (h *Handler) DeleteCollaboratorByPodcastId(ctx *gin.Context) {
 
 	_, err = h.ClientCollaboration.DeleteCollaboratorByPodcastId(tctx, req)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err.Error(),
-			"Message": "error while deleting collaborator by podcastId",
-		})
-		log.Println("error while deleting collaborator by podcastId ", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "error while deleting collaborator by podcastId", "error", err)
 		return
 	}
 
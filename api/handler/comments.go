@@ -1,40 +1,31 @@
 package handler
 
 import (
+	"api_gateway/api/response"
 	pbc "api_gateway/genproto/comments"
 	"context"
-	"encoding/json"
-	"log"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/pkg/errors"
 )
 
 func (h *Handler) CreateCommentByPodcastId(ctx *gin.Context) {
 
 	req := &pbc.CreateComment{}
-	err := json.NewDecoder(ctx.Request.Body).Decode(&req)
-	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err,
-			"Message": "Error while decoding",
-		})
-		log.Println("Error while decoding ", err)
+	if !bind(ctx, req) {
 		return
 	}
 
 	podcastId := ctx.Param("id")
-	_, err = uuid.Parse(podcastId)
+	_, err := uuid.Parse(podcastId)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err,
-			"Message": "no id or invalid uuid",
-		})
-		log.Println("no id or invalid uuid ", err)
+		response.Abort(ctx, http.StatusBadRequest, "no id or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no id or invalid uuid", "error", err)
 		return
 	}
 
@@ -45,63 +36,67 @@ func (h *Handler) CreateCommentByPodcastId(ctx *gin.Context) {
 
 	_, err = h.ClientComments.CreateCommentByPodcastId(tctx, req)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err,
-			"Message": "error while posting comment by podcastId",
-		})
-		log.Println("error while posting comment by podcastId ", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "error while posting comment by podcastId", "error", err)
 		return
 	}
 
 }
 
 func (h *Handler) GetCommentsByPodcastId(ctx *gin.Context) {
+	if comments, page, ok := h.podcastComments(ctx); ok {
+		response.Links(ctx, page, len(comments.Comments))
+		response.JSON(ctx, http.StatusOK, comments)
+	}
+}
 
-	req := &pbc.CommentFilter{}
+// GetCommentsByPodcastIdV2 returns the comments in the paginated envelope.
+func (h *Handler) GetCommentsByPodcastIdV2(ctx *gin.Context) {
+	if comments, page, ok := h.podcastComments(ctx); ok {
+		response.Page(ctx, http.StatusOK, comments, page)
+	}
+}
+
+func (h *Handler) podcastComments(ctx *gin.Context) (*pbc.AllComments, response.Pagination, bool) {
 
 	podcastId := ctx.Param("id")
 	_, err := uuid.Parse(podcastId)
 	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err,
-			"Message": "no id or invalid uuid",
-		})
-		log.Println("no id or invalid uuid ", err)
-		return
+		response.Abort(ctx, http.StatusBadRequest, "no id or invalid uuid: "+err.Error())
+		slog.DebugContext(ctx, "no id or invalid uuid", "error", err)
+		return nil, response.Pagination{}, false
 	}
-	req.Id = podcastId
 
-	limit, err := strconv.Atoi(ctx.Query("limit"))
-	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
-	}
-	offset, err := strconv.Atoi(ctx.Query("offset"))
-	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
+	list := "podcasts/" + podcastId + "/comments"
+	page, after, ok := h.cursorPagination(ctx, list)
+	if !ok {
+		return nil, page, false
 	}
 
-	req.Limit = int32(limit)
-	req.Offset = int32(offset)
-
-	tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	comments, err := h.ClientComments.GetCommentsByPodcastId(tctx, req)
-	if err != nil {
-		ctx.IndentedJSON(http.StatusBadRequest, gin.H{
-			"Error":   err,
-			"Message": "error while posting comment by podcastId",
-		})
-		log.Println("error while posting comment by podcastId ", err)
-		return
+	src := feedSource[*pbc.Comment]{
+		list: list,
+		fetch: func(ctx context.Context, offset, limit int) ([]*pbc.Comment, error) {
+			tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			comments, err := h.ClientComments.GetCommentsByPodcastId(tctx, &pbc.CommentFilter{
+				Id:     podcastId,
+				Limit:  int32(limit),
+				Offset: int32(offset),
+			})
+			return comments.GetComments(), err
+		},
+		position: commentPosition,
 	}
+	comments, ok := feed(ctx, h, src, &page, after)
+	if !ok {
+		return nil, page, false
+	}
+	return &pbc.AllComments{Comments: comments}, page, true
+}
 
-	ctx.IndentedJSON(http.StatusOK, comments)
-
+// commentPosition identifies a comment, which has no id of its own, by a
+// digest of its author, time and content.
+func commentPosition(comment *pbc.Comment) (string, string) {
+	sum := sha256.Sum256([]byte(comment.Username + "\x00" + comment.CreatedAt + "\x00" + comment.Content))
+	return comment.CreatedAt, base64.RawURLEncoding.EncodeToString(sum[:12])
 }
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"api_gateway/api/middleware"
+	"api_gateway/api/response"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var errUnknownFields = errors.New("unknown fields")
+
+// msgpackHandle follows the current msgpack spec, which tells str from bin.
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+// bind decodes the request body into msg according to its Content-Type and
+// checks it against requestRules. JSON and msgpack bodies take fields by
+// proto or lowerCamel name, as protojson does; protobuf bodies are the wire
+// encoding of msg. On routes marked strict, unknown fields are rejected.
+// msg is reset before decoding, so fields taken from the path must be set
+// after bind. When the body cannot be decoded or is invalid it writes the
+// problem response and returns false.
+func bind(ctx *gin.Context, msg proto.Message) bool {
+	err := decode(ctx, msg)
+	if err == nil {
+		return valid(ctx, msg)
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		response.Abort(ctx, http.StatusRequestEntityTooLarge,
+			"request body exceeds "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes")
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		response.Abort(ctx, http.StatusRequestTimeout, "request body was not received in time")
+	case errors.Is(err, io.EOF):
+		response.Abort(ctx, http.StatusBadRequest, "request body is empty")
+	default:
+		response.Abort(ctx, http.StatusBadRequest, "invalid request body: "+err.Error())
+	}
+	slog.DebugContext(ctx, "invalid request body", "error", err)
+
+	return false
+}
+
+// decode reads the request body into msg. JSON is decoded as it arrives,
+// so the bytes fields of uploads are not held twice; protobuf and msgpack
+// bodies are read whole first.
+func decode(ctx *gin.Context, msg proto.Message) error {
+	strict := ctx.GetBool(middleware.StrictJSONKey)
+	mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	format, ok := response.Format(mediaType)
+	if !ok {
+		format = response.MIMEJSON
+	}
+	if format == response.MIMEJSON {
+		return decodeJSON(ctx.Request.Body, ctx.Request.ContentLength, msg, strict)
+	}
+
+	body, err := readBody(ctx.Request)
+	if err == nil && len(bytes.TrimSpace(body)) == 0 {
+		err = io.EOF
+	}
+	if err != nil {
+		return err
+	}
+	if format == response.MIMEProtobuf {
+		if err := proto.Unmarshal(body, msg); err != nil {
+			return err
+		}
+		if strict && len(msg.ProtoReflect().GetUnknown()) > 0 {
+			return errUnknownFields
+		}
+		return nil
+	}
+	if body, err = msgpackToJSON(body); err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: !strict}.Unmarshal(body, msg)
+}
+
+// readBody reads the whole request body. The buffer is sized from
+// Content-Length, which BodyLimit has already checked against the route's
+// limit, so that it is not regrown while it is read.
+func readBody(r *http.Request) ([]byte, error) {
+	var buf bytes.Buffer
+	if r.ContentLength > 0 {
+		buf.Grow(int(r.ContentLength))
+	}
+	_, err := buf.ReadFrom(r.Body)
+	return buf.Bytes(), err
+}
+
+// msgpackToJSON re-encodes a msgpack body as JSON, with binary values as
+// the base64 strings protojson expects for bytes fields.
+func msgpackToJSON(body []byte) ([]byte, error) {
+	var value any
+	if err := codec.NewDecoderBytes(body, msgpackHandle).Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue(value))
+}
+
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			name, ok := key.(string)
+			if !ok {
+				name = fmt.Sprint(key)
+			}
+			m[name] = jsonValue(value)
+		}
+		return m
+	case map[string]any:
+		for key, value := range v {
+			v[key] = jsonValue(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+	}
+	return v
+}
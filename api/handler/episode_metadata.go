@@ -1,11 +1,11 @@
 package handler
 
 import (
+	"api_gateway/api/response"
 	pb "api_gateway/genproto/episode_metadata"
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,59 +14,66 @@ import (
 )
 
 func (h *Handler) GetTrendingPodcasts(c *gin.Context) {
-	limit, err := strconv.Atoi(c.Query("limit"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
+	if podcasts, page, ok := h.trendingPodcasts(c); ok {
+		response.Links(c, page, len(podcasts.Podcasts))
+		response.JSON(c, http.StatusOK, gin.H{"Trending Podcasts": podcasts})
 	}
-	offset, err := strconv.Atoi(c.Query("offset"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
+}
+
+// GetTrendingPodcastsV2 returns the podcasts in the paginated envelope
+// rather than wrapping them under a "Trending Podcasts" key.
+func (h *Handler) GetTrendingPodcastsV2(c *gin.Context) {
+	if podcasts, page, ok := h.trendingPodcasts(c); ok {
+		response.Page(c, http.StatusOK, podcasts, page)
+	}
+}
+
+func (h *Handler) trendingPodcasts(c *gin.Context) (*pb.Podcasts, response.Pagination, bool) {
+	page, ok := h.pagination(c)
+	if !ok {
+		return nil, page, false
 	}
 
 	ctx, cancel := context.WithTimeout(c, time.Second*5)
 	defer cancel()
 
 	podcasts, err := h.ClientEpisodeMetadata.GetTrendingPodcasts(ctx, &pb.Pagination{
-		Limit:  int64(limit),
-		Offset: int64(offset),
+		Limit:  int64(page.Limit),
+		Offset: int64(page.Offset),
 	})
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to get trending podcasts").Error()})
-		log.Println(err)
-		return
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "GetTrendingPodcasts failed", "error", err)
+		return nil, page, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Trending Podcasts": podcasts})
+	return podcasts, page, true
 }
 
 func (h *Handler) GetRecommendedPodcasts(c *gin.Context) {
+	if podcasts, page, ok := h.recommendedPodcasts(c); ok {
+		response.Links(c, page, len(podcasts.Podcasts))
+		response.JSON(c, http.StatusOK, gin.H{"Recommended Podcasts": podcasts})
+	}
+}
+
+func (h *Handler) GetRecommendedPodcastsV2(c *gin.Context) {
+	if podcasts, page, ok := h.recommendedPodcasts(c); ok {
+		response.Page(c, http.StatusOK, podcasts, page)
+	}
+}
+
+func (h *Handler) recommendedPodcasts(c *gin.Context) (*pb.Podcasts, response.Pagination, bool) {
 	id := c.Param("userid")
 	_, err := uuid.Parse(id)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid user id").Error()})
-		log.Println(err)
-		return
+		response.Abort(c, http.StatusBadRequest, errors.Wrap(err, "invalid user id").Error())
+		slog.DebugContext(c, "invalid user id", "error", err)
+		return nil, response.Pagination{}, false
 	}
-	limit, err := strconv.Atoi(c.Query("limit"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
-	}
-	offset, err := strconv.Atoi(c.Query("offset"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
+	page, ok := h.pagination(c)
+	if !ok {
+		return nil, page, false
 	}
 
 	ctx, cancel := context.WithTimeout(c, time.Second*5)
@@ -74,33 +81,35 @@ func (h *Handler) GetRecommendedPodcasts(c *gin.Context) {
 
 	podcasts, err := h.ClientEpisodeMetadata.GetRecommendedPodcasts(ctx, &pb.IdPage{
 		Id:         id,
-		Pagination: &pb.Pagination{Limit: int64(limit), Offset: int64(offset)},
+		Pagination: &pb.Pagination{Limit: int64(page.Limit), Offset: int64(page.Offset)},
 	})
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to get recommended podcasts").Error()})
-		log.Println(err)
-		return
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "GetRecommendedPodcasts failed", "error", err)
+		return nil, page, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Recommended Podcasts": podcasts})
+	return podcasts, page, true
 }
 
 func (h *Handler) GetPodcastsByGenre(c *gin.Context) {
-	genres := c.QueryArray("genres")
-	limit, err := strconv.Atoi(c.Query("limit"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
+	if podcasts, page, ok := h.podcastsByGenre(c); ok {
+		response.Links(c, page, len(podcasts.Podcasts))
+		response.JSON(c, http.StatusOK, gin.H{"Podcasts": podcasts})
 	}
-	offset, err := strconv.Atoi(c.Query("offset"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
+}
+
+func (h *Handler) GetPodcastsByGenreV2(c *gin.Context) {
+	if podcasts, page, ok := h.podcastsByGenre(c); ok {
+		response.Page(c, http.StatusOK, podcasts, page)
+	}
+}
+
+func (h *Handler) podcastsByGenre(c *gin.Context) (*pb.Podcasts, response.Pagination, bool) {
+	genres := c.QueryArray("genres")
+	page, ok := h.pagination(c)
+	if !ok {
+		return nil, page, false
 	}
 
 	ctx, cancel := context.WithTimeout(c, time.Second*5)
@@ -108,38 +117,51 @@ func (h *Handler) GetPodcastsByGenre(c *gin.Context) {
 
 	podcasts, err := h.ClientEpisodeMetadata.GetPodcastsByGenre(ctx, &pb.Filter{
 		Genres:     genres,
-		Pagination: &pb.Pagination{Limit: int64(limit), Offset: int64(offset)},
+		Pagination: &pb.Pagination{Limit: int64(page.Limit), Offset: int64(page.Offset)},
 	})
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to get podcasts by genre").Error()})
-		log.Println(err)
-		return
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "GetPodcastsByGenre failed", "error", err)
+		return nil, page, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Podcasts": podcasts})
+	return podcasts, page, true
 }
 
 func (h *Handler) SearchPodcast(c *gin.Context) {
 	var title pb.Title
-	err := c.BindJSON(&title)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid data").Error()})
-		log.Println(err)
+	if !bind(c, &title) {
 		return
 	}
+	if episode, ok := h.searchEpisode(c, &title); ok {
+		response.JSON(c, http.StatusOK, gin.H{"Episode": episode})
+	}
+}
 
+// SearchPodcastV2 takes the title from the query string, so the search can
+// be cached and linked like any other GET.
+func (h *Handler) SearchPodcastV2(c *gin.Context) {
+	title := c.Query("title")
+	if title == "" {
+		response.Abort(c, http.StatusBadRequest, "title query parameter required")
+		slog.DebugContext(c, "title query parameter required")
+		return
+	}
+	if episode, ok := h.searchEpisode(c, &pb.Title{EpisodeTitle: title}); ok {
+		response.JSON(c, http.StatusOK, episode)
+	}
+}
+
+func (h *Handler) searchEpisode(c *gin.Context, title *pb.Title) (*pb.Episode, bool) {
 	ctx, cancel := context.WithTimeout(c, time.Second*5)
 	defer cancel()
 
-	episode, err := h.ClientEpisodeMetadata.SearchEpisode(ctx, &title)
+	episode, err := h.ClientEpisodeMetadata.SearchEpisode(ctx, title)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to find episode").Error()})
-		log.Println(err)
-		return
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "SearchEpisode failed", "error", err)
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Episode": episode})
+	return episode, true
 }
@@ -1,52 +1,41 @@
 package handler
 
 import (
+	"api_gateway/api/response"
 	pbmetadat "api_gateway/genproto/episode_metadata"
 	pb "api_gateway/genproto/episodes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 )
 
 func (h *Handler) CreatePodcastEpisode(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err),
-		})
-		log.Printf("Error with getting Id from URL: %s", err)
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 
-	req := pb.EpisodeCreate{PodcastId: id}
-	err := json.NewDecoder(ctx.Request.Body).Decode(&req)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting data from URL body: %s", err),
-		})
-		log.Printf("Error with getting data from URL body: %s", err)
+	req := pb.EpisodeCreate{}
+	if !bind(ctx, &req) {
 		return
 	}
+	req.PodcastId = id
 
 	nestedctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 	resp, err := h.ClientEpisodes.CreatePodcastEpisode(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err),
-		})
-		log.Printf("Error with request to podcasts service: %s", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
 
@@ -61,93 +50,129 @@ func (h *Handler) CreatePodcastEpisode(ctx *gin.Context) {
 	defer cancel()
 	_, err = h.ClientEpisodeMetadata.CreateEpisodeMetaData(nestedctx1, &req2)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with creating episode metadate: %s", err),
-		})
-		log.Printf("Error with creating episode metadate: %s", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with creating episode metadate", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.JSON(ctx, http.StatusAccepted, resp)
 }
 
 func (h *Handler) GetEpisodesByPodcastId(ctx *gin.Context) {
+	if resp, page, ok := h.podcastEpisodes(ctx); ok {
+		response.Links(ctx, page, len(resp.Episodes))
+		response.ConditionalJSON(ctx, http.StatusAccepted, resp)
+	}
+}
+
+// GetEpisodesByPodcastIdV2 returns the episodes in the paginated envelope,
+// with 200 rather than 202.
+func (h *Handler) GetEpisodesByPodcastIdV2(ctx *gin.Context) {
+	if resp, page, ok := h.podcastEpisodes(ctx); ok {
+		response.Page(ctx, http.StatusOK, resp, page)
+	}
+}
+
+func (h *Handler) podcastEpisodes(ctx *gin.Context) (*pb.Episodes, response.Pagination, bool) {
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err),
-		})
-		log.Printf("Error with getting Id from URL: %s", err)
-		return
-	}
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
+		return nil, response.Pagination{}, false
+	}
+
+	list := "podcasts/" + id + "/episodes"
+	page, after, ok := h.cursorPagination(ctx, list)
+	if !ok {
+		return nil, page, false
+	}
+
+	src := feedSource[*pb.Episode]{
+		list: list,
+		fetch: func(ctx context.Context, offset, limit int) ([]*pb.Episode, error) {
+			nestedctx, cancel := context.WithTimeout(ctx, time.Second*5)
+			defer cancel()
+			resp, err := h.ClientEpisodes.GetEpisodesByPodcastId(nestedctx, &pb.Filter{
+				Id:     id,
+				Limit:  int32(limit),
+				Offset: int32(offset),
+			})
+			return resp.GetEpisodes(), err
+		},
+		position: func(episode *pb.Episode) (string, string) {
+			return episode.CreatedAt, episode.Id
+		},
+	}
+	episodes, ok := feed(ctx, h, src, &page, after)
+	if !ok {
+		return nil, page, false
+	}
+	return &pb.Episodes{Episodes: episodes}, page, true
+}
 
-	limit, err := strconv.Atoi(ctx.Query("limit"))
-	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
+// GetEpisode serves a single episode, with the ETag UpdateEpisode checks
+// If-Match against.
+func (h *Handler) GetEpisode(ctx *gin.Context) {
+	podcastId := ctx.Param("id")
+	if _, err := uuid.Parse(podcastId); err != nil {
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
-	offset, err := strconv.Atoi(ctx.Query("offset"))
-	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
+	episodeId := ctx.Param("episodeid")
+	if _, err := uuid.Parse(episodeId); err != nil {
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 
-	req := pb.Filter{
-		Id:     id,
-		Limit:  int32(limit),
-		Offset: int32(offset),
-	}
-
 	nestedctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
-	resp, err := h.ClientEpisodes.GetEpisodesByPodcastId(nestedctx, &req)
+	episode, err := h.findEpisode(nestedctx, podcastId, episodeId)
+	if errors.Is(err, errVersionUnknown) {
+		response.Abort(ctx, http.StatusServiceUnavailable, err.Error())
+		slog.WarnContext(ctx, "episode lookup gave up", "error", err)
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err),
-		})
-		log.Printf("Error with request to podcasts service: %s", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	if episode == nil {
+		response.Abort(ctx, http.StatusNotFound, "episode not found")
+		return
+	}
+	response.ConditionalJSON(ctx, http.StatusOK, episode)
 }
 
 func (h *Handler) UpdateEpisode(ctx *gin.Context) {
 	podcastId := ctx.Param("id")
 	if _, err := uuid.Parse(podcastId); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err),
-		})
-		log.Printf("Error with getting Id from URL: %s", err)
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 	episodeId := ctx.Param("episodeid")
 	if _, err := uuid.Parse(episodeId); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err),
-		})
-		log.Printf("Error with getting Id from URL: %s", err)
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 
+	episode := &pb.EpisodeCreate{}
+	if !bind(ctx, episode) {
+		return
+	}
 	req := pb.IDs{
 		PodcastId: podcastId,
 		EpisodeId: episodeId,
+		Episode:   episode,
 	}
-	err := json.NewDecoder(ctx.Request.Body).Decode(&req.Episode)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting data from URL body: %s", err),
-		})
-		log.Printf("Error with getting data from URL body: %s", err)
+
+	current := func(ctx context.Context) (proto.Message, error) {
+		return h.findEpisode(ctx, podcastId, episodeId)
+	}
+	if !ifMatch(ctx, current) {
 		return
 	}
 
@@ -155,33 +180,24 @@ func (h *Handler) UpdateEpisode(ctx *gin.Context) {
 	defer cancel()
 	resp, err := h.ClientEpisodes.UpdateEpisode(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err),
-		})
-		log.Printf("Error with request to podcasts service: %s", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.JSON(ctx, http.StatusAccepted, resp)
 }
 
 func (h *Handler) DeleteEpisode(ctx *gin.Context) {
 	podcastId := ctx.Param("id")
 	if _, err := uuid.Parse(podcastId); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err),
-		})
-		log.Printf("Error with getting Id from URL: %s", err)
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 	episodeId := ctx.Param("episodeid")
 	if _, err := uuid.Parse(episodeId); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err),
-		})
-		log.Printf("Error with getting Id from URL: %s", err)
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 
@@ -194,12 +210,45 @@ func (h *Handler) DeleteEpisode(ctx *gin.Context) {
 	defer cancel()
 	resp, err := h.ClientEpisodes.DeleteEpisode(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err),
-		})
-		log.Printf("Error with request to podcasts service: %s", err)
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.JSON(ctx, http.StatusAccepted, resp)
+}
+
+// episodeScanPage is the page size used to look an episode up, as the
+// episodes service cannot fetch a single episode, and episodeScanPages
+// bounds how many pages are searched, so a lookup costs at most that many
+// backend calls.
+const (
+	episodeScanPage  = 100
+	episodeScanPages = 5
+)
+
+// findEpisode returns the episode of the podcast with the given id, or nil
+// when the podcast has no such episode. It returns errVersionUnknown when
+// the episode is not among the first episodeScanPages pages of a podcast
+// that has more.
+func (h *Handler) findEpisode(ctx context.Context, podcastId, episodeId string) (proto.Message, error) {
+	for i := int32(0); i < episodeScanPages; i++ {
+		page, err := h.ClientEpisodes.GetEpisodesByPodcastId(ctx, &pb.Filter{
+			Id:     podcastId,
+			Limit:  episodeScanPage,
+			Offset: i * episodeScanPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, episode := range page.Episodes {
+			if episode.Id == episodeId {
+				return episode, nil
+			}
+		}
+		if len(page.Episodes) < episodeScanPage {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: episode is not among the first %d of the podcast",
+		errVersionUnknown, episodeScanPage*episodeScanPages)
 }
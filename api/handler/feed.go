@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"api_gateway/api/cursor"
+	"api_gateway/api/response"
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedSource fetches a window of a list the backend serves by offset, and
+// tells where each of its items sorts. list names it in the cursors issued
+// for it.
+type feedSource[T any] struct {
+	list     string
+	fetch    func(ctx context.Context, offset, limit int) ([]T, error)
+	position func(item T) (key, id string)
+}
+
+// cursorPagination reads the pagination of the feed named list, which may
+// resume from the cursor of a previous page of it instead of an offset.
+func (h *Handler) cursorPagination(ctx *gin.Context, list string) (response.Pagination, *cursor.Cursor, bool) {
+	page, ok := h.pagination(ctx)
+	token := ctx.Query("cursor")
+	if !ok || token == "" {
+		return page, nil, ok
+	}
+
+	var violations []response.FieldViolation
+	if ctx.Query("offset") != "" {
+		violations = append(violations, response.FieldViolation{Field: "offset", Description: "cannot be combined with cursor"})
+	}
+	after, err := h.Cursors.Decode(list, token)
+	if err != nil {
+		slog.DebugContext(ctx, "invalid cursor", "error", err)
+		violations = append(violations, response.FieldViolation{Field: "cursor", Description: "is malformed or was not issued by this gateway for this list"})
+	}
+	if len(violations) > 0 {
+		response.AbortWithViolations(ctx, "invalid pagination parameters", violations)
+		slog.DebugContext(ctx, "invalid pagination parameters", "violations", violations)
+		return page, nil, false
+	}
+	return page, &after, true
+}
+
+// feed fetches a page of src. After a cursor, the page starts right past
+// the item it points at, wherever items added or removed since have moved
+// it, so that none are skipped or repeated. page gets the offset the page
+// starts at and, when it is full, the cursor of the next one. On failure it
+// writes the problem response and returns false.
+func feed[T any](ctx *gin.Context, h *Handler, src feedSource[T], page *response.Pagination, after *cursor.Cursor) ([]T, bool) {
+	if after != nil {
+		offset, ok := locate(ctx, h, src, after)
+		if !ok {
+			return nil, false
+		}
+		page.Offset = offset
+	}
+
+	items, err := src.fetch(ctx, page.Offset, page.Limit)
+	if err != nil {
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "error while fetching feed page", "error", err)
+		return nil, false
+	}
+	if len(items) > 0 && len(items) >= page.Limit {
+		key, id := src.position(items[len(items)-1])
+		page.NextCursor = h.Cursors.Encode(cursor.Cursor{List: src.list, Key: key, ID: id, Offset: page.Offset + len(items) - 1})
+	}
+	return items, true
+}
+
+// locate returns the offset just past the item after points at. The
+// offset in the cursor is only a hint: the item is looked for in the one
+// window of PageLimitMax items centred on it, so it is found as long as
+// fewer than half that many items were added or removed before it. With the
+// page served, a cursor request makes two backend calls. When the item
+// itself is gone, the first item of the window sorting past it is used
+// instead, provided the window shows which way the list sorts and holds
+// where the item was. Otherwise the cursor is answered 410.
+func locate[T any](ctx *gin.Context, h *Handler, src feedSource[T], after *cursor.Cursor) (int, bool) {
+	start := max(after.Offset-h.PageLimitMax/2, 0)
+	items, err := src.fetch(ctx, start, h.PageLimitMax)
+	if err != nil {
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "error while locating feed cursor", "error", err)
+		return 0, false
+	}
+
+	keys := make([]string, len(items))
+	for j, item := range items {
+		key, id := src.position(item)
+		if key == after.Key && id == after.ID {
+			return start + j + 1, true
+		}
+		keys[j] = key
+	}
+	exhausted := len(items) < h.PageLimitMax
+
+	if len(keys) > 1 && keys[0] != keys[len(keys)-1] {
+		descending := keys[0] > keys[len(keys)-1]
+		past := len(keys)
+		for j, key := range keys {
+			if (descending && key < after.Key) || (!descending && key > after.Key) {
+				past = j
+				break
+			}
+		}
+		// The item sorted inside the window unless every item of it sorts
+		// past the item, or none does and the list goes on.
+		if (past > 0 || start == 0) && (past < len(keys) || exhausted) {
+			return start + past, true
+		}
+	} else if exhausted && start == 0 {
+		return len(keys), true
+	}
+
+	response.Abort(ctx, http.StatusGone, "cursor no longer points into the list; start again from the first page")
+	slog.DebugContext(ctx, "feed cursor not found", "key", after.Key, "offset", after.Offset)
+	return 0, false
+}
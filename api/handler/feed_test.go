@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"api_gateway/api/cursor"
+	"api_gateway/api/response"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type feedItem struct{ key, id string }
+
+// newestFirst returns the items numbered newest down to oldest, sorted by
+// key descending as feeds are.
+func newestFirst(newest, oldest int) []feedItem {
+	var items []feedItem
+	for i := newest; i >= oldest; i-- {
+		items = append(items, feedItem{key: fmt.Sprintf("2024-01-01T00:%05d", i), id: fmt.Sprint(i)})
+	}
+	return items
+}
+
+// TestFeedCursor checks where a page resumes after a cursor once items were
+// added or removed before the item it points at, and that a cursor whose
+// item moved out of the searched window is answered 410.
+func TestFeedCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{PageLimitMax: 100, Cursors: cursor.NewCodec([]byte("test"))}
+	const limit = 20
+
+	for _, tc := range []struct {
+		name string
+		// change turns the list the cursor was issued on into the one it
+		// is used on.
+		change func(list []feedItem) []feedItem
+		status int
+		// first is the id of the first item of the page after the cursor.
+		first string
+	}{
+		{"unchanged", func(list []feedItem) []feedItem { return list }, http.StatusOK, "480"},
+		{"items added", func(list []feedItem) []feedItem {
+			return append(newestFirst(1040, 1001), list...)
+		}, http.StatusOK, "480"},
+		{"items removed", func(list []feedItem) []feedItem {
+			return slices.Delete(list, 100, 140)
+		}, http.StatusOK, "480"},
+		{"item removed", func(list []feedItem) []feedItem {
+			return slices.Delete(list, 519, 520)
+		}, http.StatusOK, "480"},
+		{"scrolled out by additions", func(list []feedItem) []feedItem {
+			return append(newestFirst(1060, 1001), list...)
+		}, http.StatusGone, ""},
+		{"scrolled out by removals", func(list []feedItem) []feedItem {
+			return slices.Delete(list, 100, 160)
+		}, http.StatusGone, ""},
+	} {
+		// The cursor points at the item at offset 519, the last of the page
+		// at offset 500.
+		list := newestFirst(1000, 1)
+		after := cursor.Cursor{List: "feed", Key: list[519].key, ID: list[519].id, Offset: 519}
+		list = tc.change(list)
+
+		fetches := 0
+		src := feedSource[feedItem]{
+			list: "feed",
+			fetch: func(ctx context.Context, offset, limit int) ([]feedItem, error) {
+				fetches++
+				offset = min(offset, len(list))
+				return list[offset:min(offset+limit, len(list))], nil
+			},
+			position: func(item feedItem) (string, string) { return item.key, item.id },
+		}
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/feed", nil)
+		page := response.Pagination{Limit: limit}
+		items, ok := feed(ctx, h, src, &page, &after)
+
+		switch {
+		case tc.status == http.StatusOK && !ok:
+			t.Errorf("%s: failed with %d", tc.name, w.Code)
+		case tc.status == http.StatusOK && (len(items) == 0 || items[0].id != tc.first):
+			t.Errorf("%s: page starts at %v, want item %s", tc.name, items, tc.first)
+		case tc.status != http.StatusOK && (ok || w.Code != tc.status):
+			t.Errorf("%s: status %d, ok %v; want %d", tc.name, w.Code, ok, tc.status)
+		}
+		if fetches > 2 {
+			t.Errorf("%s: %d backend calls, want at most 2", tc.name, fetches)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"api_gateway/api/cursor"
 	"api_gateway/config"
 	pbAuthentication "api_gateway/genproto/authentication"
 	pbCollaboration "api_gateway/genproto/collaborations"
@@ -22,6 +23,10 @@ type Handler struct {
 	ClientPodcasts         pbPodcasts.PodcastsClient
 	ClientUserManagement   pbUserManagement.UserManagementClient
 	ClientUserInteractions pbUserInteractions.UserInteractionsClient
+
+	PageLimitDefault int
+	PageLimitMax     int
+	Cursors          *cursor.Codec
 }
 
 func NewHandler(cfg *config.Config) *Handler {
@@ -35,5 +40,18 @@ func NewHandler(cfg *config.Config) *Handler {
 		ClientPodcasts:         pkg.NewPodcastsClient(cfg),
 		ClientUserManagement:   pkg.NewUserManagementClient(cfg),
 		ClientUserInteractions: pkg.NewUserInteractionsClient(cfg),
+
+		PageLimitDefault: cfg.PAGE_LIMIT_DEFAULT,
+		PageLimitMax:     cfg.PAGE_LIMIT_MAX,
+		Cursors:          cursor.NewCodec(cursorKey(cfg)),
+	}
+}
+
+// cursorKey returns the key cursors are signed with. Unless a separate
+// CURSOR_SIGNING_KEY is configured, it is derived from SIGNING_KEY.
+func cursorKey(cfg *config.Config) []byte {
+	if cfg.CURSOR_SIGNING_KEY != "" && cfg.CURSOR_SIGNING_KEY != cfg.SIGNING_KEY {
+		return []byte(cfg.CURSOR_SIGNING_KEY)
 	}
+	return cursor.DeriveKey([]byte(cfg.SIGNING_KEY))
 }
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var errJSONSyntax = errors.New("malformed JSON object")
+
+// decodeJSON decodes the JSON object read from r into msg as protojson
+// does. The base64 strings of singular bytes fields, such as the audio of
+// an upload, are decoded as they are read; the other members are collected
+// and handed to protojson. The body is so never held whole, and an upload
+// takes about the size of its decoded audio. sizeHint is the length of the
+// body, when known, to size that buffer up front.
+func decodeJSON(r io.Reader, sizeHint int64, msg proto.Message, strict bool) error {
+	br := bufio.NewReader(r)
+	c, err := nextByte(br)
+	if err != nil {
+		return err
+	}
+	if c != '{' {
+		return fmt.Errorf("%w: body is not an object", errJSONSyntax)
+	}
+
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	streamed := map[protoreflect.FieldDescriptor][]byte{}
+	seen := map[protoreflect.FieldDescriptor]bool{}
+	var rest, key bytes.Buffer
+	rest.WriteByte('{')
+
+	c, err = nextByte(br)
+	for err == nil && c != '}' {
+		if c != '"' {
+			return fmt.Errorf("%w: expected a member name", errJSONSyntax)
+		}
+		key.Reset()
+		key.WriteByte('"')
+		if err := copyString(br, &key); err != nil {
+			return err
+		}
+		var name string
+		if err := json.Unmarshal(key.Bytes(), &name); err != nil {
+			return err
+		}
+		if c, err = nextByte(br); err != nil {
+			return unexpectedEOF(err)
+		}
+		if c != ':' {
+			return fmt.Errorf("%w: expected ':' after %q", errJSONSyntax, name)
+		}
+		if c, err = nextByte(br); err != nil {
+			return unexpectedEOF(err)
+		}
+
+		fd := fields.ByJSONName(name)
+		if fd == nil {
+			fd = fields.ByTextName(name)
+		}
+		if fd != nil && fd.Kind() == protoreflect.BytesKind && fd.Cardinality() != protoreflect.Repeated {
+			if seen[fd] {
+				return fmt.Errorf("duplicate field %q", name)
+			}
+			seen[fd] = true
+		}
+		if seen[fd] && c == '"' {
+			b, err := decodeBase64String(br, sizeHint)
+			if err != nil {
+				return fmt.Errorf("invalid value for bytes field %s: %w", name, err)
+			}
+			streamed[fd] = b
+		} else {
+			if rest.Len() > 1 {
+				rest.WriteByte(',')
+			}
+			rest.Write(key.Bytes())
+			rest.WriteByte(':')
+			if err := copyValue(br, c, &rest); err != nil {
+				return err
+			}
+		}
+
+		if c, err = nextByte(br); err != nil {
+			break
+		}
+		if c == ',' {
+			if c, err = nextByte(br); err == nil && c == '}' {
+				return fmt.Errorf("%w: trailing comma", errJSONSyntax)
+			}
+		} else if c != '}' {
+			return fmt.Errorf("%w: expected ',' or '}' after %q", errJSONSyntax, name)
+		}
+	}
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if _, err := nextByte(br); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("%w: data after the object", errJSONSyntax)
+		}
+		return err
+	}
+	rest.WriteByte('}')
+
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: !strict}).Unmarshal(rest.Bytes(), msg); err != nil {
+		return err
+	}
+	m := msg.ProtoReflect()
+	for fd, b := range streamed {
+		m.Set(fd, protoreflect.ValueOfBytes(b))
+	}
+	return nil
+}
+
+// nextByte returns the next byte of r that is not JSON whitespace.
+func nextByte(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return c, nil
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// copyString copies the rest of a JSON string, whose opening quote has
+// been read, up to and including its closing quote.
+func copyString(r *bufio.Reader, out *bytes.Buffer) error {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		out.WriteByte(c)
+		switch c {
+		case '"':
+			return nil
+		case '\\':
+			escaped, err := r.ReadByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			out.WriteByte(escaped)
+		}
+	}
+}
+
+// copyValue copies the JSON value starting with c. It is not validated:
+// protojson does that once the members are collected.
+func copyValue(r *bufio.Reader, c byte, out *bytes.Buffer) error {
+	out.WriteByte(c)
+	switch c {
+	case '"':
+		return copyString(r, out)
+	case '{', '[':
+		for depth := 1; depth > 0; {
+			c, err := r.ReadByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			out.WriteByte(c)
+			switch c {
+			case '"':
+				if err := copyString(r, out); err != nil {
+					return err
+				}
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		return nil
+	}
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch c {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return r.UnreadByte()
+		}
+		out.WriteByte(c)
+	}
+}
+
+// decodeBase64String decodes the rest of a JSON string, whose opening
+// quote has been read, as base64. Like protojson it takes the standard and
+// URL alphabets, with or without padding.
+func decodeBase64String(r *bufio.Reader, sizeHint int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if sizeHint > 0 {
+		buf.Grow(int(sizeHint / 4 * 3))
+	}
+	_, err := buf.ReadFrom(base64.NewDecoder(base64.RawStdEncoding, &base64Text{r: r}))
+	return buf.Bytes(), err
+}
+
+// base64Text reads the characters of a JSON string holding base64, mapped
+// to the unpadded standard alphabet, until its closing quote.
+type base64Text struct {
+	r    *bufio.Reader
+	done bool
+}
+
+func (t *base64Text) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && !t.done {
+		// Copy the run of plain base64 characters already buffered.
+		buffered, _ := t.r.Peek(min(len(p)-n, t.r.Buffered()))
+		run := bytes.IndexAny(buffered, "\"=-_\\")
+		if run < 0 {
+			run = len(buffered)
+		}
+		if run > 0 {
+			n += copy(p[n:], buffered[:run])
+			t.r.Discard(run)
+			continue
+		}
+
+		c, err := t.r.ReadByte()
+		if err != nil {
+			return n, unexpectedEOF(err)
+		}
+		switch c {
+		case '"':
+			t.done = true
+		case '=':
+		case '-':
+			p[n] = '+'
+			n++
+		case '_':
+			p[n] = '/'
+			n++
+		case '\\':
+			escaped, err := t.r.ReadByte()
+			if err != nil {
+				return n, unexpectedEOF(err)
+			}
+			switch escaped {
+			case '/':
+				p[n] = '/'
+				n++
+			case 'n', 'r':
+				// Line breaks are ignored, as by the base64 decoder.
+			default:
+				return n, fmt.Errorf("unsupported escape \\%c in base64", escaped)
+			}
+		default:
+			p[n] = c
+			n++
+		}
+	}
+	if n == 0 && t.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	pbe "api_gateway/genproto/episodes"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestDecodeJSONMatchesProtojson checks that streaming decodes the bodies
+// protojson accepts into the same message, and rejects those it rejects.
+func TestDecodeJSONMatchesProtojson(t *testing.T) {
+	audio := []byte("\xff\xfe\xfd audio \x00 with every \xfb\xff base64 character")
+	std := base64.StdEncoding.EncodeToString(audio)
+	url := base64.RawURLEncoding.EncodeToString(audio)
+	escaped := strings.ReplaceAll(std, "/", `\/`)
+
+	for _, tc := range []struct {
+		name, body string
+		strict     bool
+	}{
+		{"proto names", `{"podcast_id":"p","title":"t","file_audio":"` + std + `","duration":"90","tags":["a","b"]}`, false},
+		{"camel names", `{"podcastId":"p","fileAudio":"` + url + `","duration":90,"tags":[]}`, false},
+		{"whitespace", " \n{ \"title\" : \"a \\\"quoted\\\" {title}\" ,\n \"file_audio\" : \"" + escaped + "\" }\n", false},
+		{"empty audio", `{"file_audio":""}`, false},
+		{"null audio", `{"file_audio":null,"title":"t"}`, false},
+		{"nested unknown", `{"title":"t","extra":{"a":[1,{"b":"}"}]},"file_audio":"` + std + `"}`, false},
+		{"unknown on strict route", `{"title":"t","extra":1}`, true},
+		{"duplicate audio", `{"file_audio":"` + std + `","fileAudio":"` + std + `"}`, false},
+		{"bad base64", `{"file_audio":"not base64!"}`, false},
+		{"trailing comma", `{"title":"t",}`, false},
+		{"trailing data", `{"title":"t"} {}`, false},
+		{"truncated", `{"title":"t","file_audio":"` + std[:8], false},
+		{"not an object", `["title"]`, false},
+	} {
+		want := &pbe.EpisodeCreate{}
+		wantErr := protojson.UnmarshalOptions{DiscardUnknown: !tc.strict}.Unmarshal([]byte(tc.body), want)
+		got := &pbe.EpisodeCreate{}
+		err := decodeJSON(strings.NewReader(tc.body), int64(len(tc.body)), got, tc.strict)
+
+		switch {
+		case (err == nil) != (wantErr == nil):
+			t.Errorf("%s: error %v, protojson error %v", tc.name, err, wantErr)
+		case err == nil && !proto.Equal(got, want):
+			t.Errorf("%s: decoded %v, protojson decoded %v", tc.name, got, want)
+		}
+	}
+}
+
+func TestDecodeJSONEmptyBody(t *testing.T) {
+	err := decodeJSON(strings.NewReader(" \n"), 2, &pbe.EpisodeCreate{}, false)
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("empty body: error %v, want io.EOF", err)
+	}
+}
+
+// TestDecodeJSONStreamsAudio checks that an upload is not buffered whole:
+// decoding it allocates about the size of its audio, not of the body.
+func TestDecodeJSONStreamsAudio(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 4<<20)
+	body := []byte(`{"title":"t","file_audio":"` + base64.StdEncoding.EncodeToString(audio) + `"}`)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	msg := &pbe.EpisodeCreate{}
+	if err := decodeJSON(bytes.NewReader(body), int64(len(body)), msg, false); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if !bytes.Equal(msg.FileAudio, audio) {
+		t.Fatal("audio decoded wrong")
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(len(audio))*5/4 {
+		t.Errorf("decoding a %d byte body with %d bytes of audio allocated %d bytes", len(body), len(audio), allocated)
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"api_gateway/api/response"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pagination reads limit and offset from the query string. Missing values
+// fall back to the first page of PageLimitDefault items and larger limits
+// are capped at PageLimitMax. Values that are not integers, or are out of
+// range, are answered with a validation problem and false.
+func (h *Handler) pagination(ctx *gin.Context) (response.Pagination, bool) {
+	page := response.Pagination{Limit: h.PageLimitDefault}
+	var violations []response.FieldViolation
+
+	if raw := ctx.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		switch {
+		case err != nil:
+			violations = append(violations, response.FieldViolation{Field: "limit", Description: "must be an integer"})
+		case limit < 1:
+			violations = append(violations, response.FieldViolation{Field: "limit", Description: "must be at least 1"})
+		default:
+			page.Limit = min(limit, h.PageLimitMax)
+		}
+	}
+	if raw := ctx.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		switch {
+		case err != nil:
+			violations = append(violations, response.FieldViolation{Field: "offset", Description: "must be an integer"})
+		case offset < 0:
+			violations = append(violations, response.FieldViolation{Field: "offset", Description: "must not be negative"})
+		case offset > math.MaxInt32:
+			violations = append(violations, response.FieldViolation{Field: "offset", Description: "must be at most " + strconv.Itoa(math.MaxInt32)})
+		default:
+			page.Offset = offset
+		}
+	}
+
+	if len(violations) > 0 {
+		response.AbortWithViolations(ctx, "invalid pagination parameters", violations)
+		slog.DebugContext(ctx, "invalid pagination parameters", "violations", violations)
+		return page, false
+	}
+	return page, true
+}
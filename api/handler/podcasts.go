@@ -1,54 +1,41 @@
 package handler
 
 import (
+	"api_gateway/api/response"
 	pb "api_gateway/genproto/podcasts"
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 )
 
 func (h *Handler) CreatePodcast(ctx *gin.Context) {
 	req := pb.PodcastCreate{}
 
-	err := json.NewDecoder(ctx.Request.Body).Decode(&req)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting data from URL body: %s", err.Error()),
-		})
-		log.Printf("Error with getting data from URL body: %s", err.Error())
+	if !bind(ctx, &req) {
 		return
 	}
 	nestedctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 	resp, err := h.ClientPodcasts.CreatePodcast(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err.Error()),
-		})
-		log.Printf("Error with request to podcasts service: %s", err.Error())
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.JSON(ctx, http.StatusAccepted, resp)
 }
 
 func (h *Handler) GetPodcastById(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err.Error()),
-		})
-		log.Printf("Error with getting Id from URL: %s", err.Error())
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err.Error()))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 	req := pb.ID{Id: id}
@@ -57,60 +44,49 @@ func (h *Handler) GetPodcastById(ctx *gin.Context) {
 	defer cancel()
 	resp, err := h.ClientPodcasts.GetPodcastById(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err.Error()),
-		})
-		log.Printf("Error with request to podcasts service: %s", err.Error())
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.ConditionalJSON(ctx, http.StatusAccepted, resp)
 }
 
 func (h *Handler) UpdatePodcast(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err.Error()),
-		})
-		log.Printf("Error with getting Id from URL: %s", err.Error())
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err.Error()))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 	req := pb.PodcastUpdate{}
-	err := json.NewDecoder(ctx.Request.Body).Decode(&req)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting data from URL body: %s", err.Error()),
-		})
-		log.Printf("Error with getting data from URL body: %s", err.Error())
+	if !bind(ctx, &req) {
 		return
 	}
 	req.Id = id
 
+	current := func(ctx context.Context) (proto.Message, error) {
+		return h.ClientPodcasts.GetPodcastById(ctx, &pb.ID{Id: id})
+	}
+	if !ifMatch(ctx, current) {
+		return
+	}
+
 	nestedctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 	resp, err := h.ClientPodcasts.UpdatePodcast(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err.Error()),
-		})
-		log.Printf("Error with request to podcasts service: %s", err.Error())
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.JSON(ctx, http.StatusAccepted, resp)
 }
 
 func (h *Handler) DeletePodcast(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err.Error()),
-		})
-		log.Printf("Error with getting Id from URL: %s", err.Error())
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err.Error()))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 	req := pb.ID{Id: id}
@@ -119,70 +95,63 @@ func (h *Handler) DeletePodcast(ctx *gin.Context) {
 	defer cancel()
 	resp, err := h.ClientPodcasts.DeletePodcast(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err.Error()),
-		})
-		log.Printf("Error with request to podcasts service: %s", err.Error())
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.JSON(ctx, http.StatusAccepted, resp)
 }
 
 func (h *Handler) GetUserPodcasts(ctx *gin.Context) {
+	if resp, page, ok := h.userPodcasts(ctx); ok {
+		response.Links(ctx, page, len(resp.Podcasts))
+		response.JSON(ctx, http.StatusAccepted, resp)
+	}
+}
+
+// GetUserPodcastsV2 returns the podcasts in the paginated envelope, with
+// 200 rather than 202.
+func (h *Handler) GetUserPodcastsV2(ctx *gin.Context) {
+	if resp, page, ok := h.userPodcasts(ctx); ok {
+		response.Page(ctx, http.StatusOK, resp, page)
+	}
+}
+
+func (h *Handler) userPodcasts(ctx *gin.Context) (*pb.UserPodcasts, response.Pagination, bool) {
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err.Error()),
-		})
-		log.Printf("Error with getting Id from URL: %s", err.Error())
-		return
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err.Error()))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
+		return nil, response.Pagination{}, false
 	}
 
-	limit, err := strconv.Atoi(ctx.Query("limit"))
-	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
-	}
-	offset, err := strconv.Atoi(ctx.Query("offset"))
-	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid pagination parameters").Error()})
-		log.Println(err)
-		return
+	page, ok := h.pagination(ctx)
+	if !ok {
+		return nil, page, false
 	}
 
 	req := pb.Filter{
 		Id:     id,
-		Limit:  int32(limit),
-		Offset: int32(offset),
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
 	}
 
 	nestedctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 	resp, err := h.ClientPodcasts.GetUserPodcasts(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err.Error()),
-		})
-		log.Printf("Error with request to podcasts service: %s", err.Error())
-		return
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
+		return nil, page, false
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	return resp, page, true
 }
 
 func (h *Handler) PublishPodcast(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if _, err := uuid.Parse(id); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{
-			"error":   "StatusBadRequest",
-			"message": fmt.Sprintf("Error with getting Id from URL: %s", err.Error()),
-		})
-		log.Printf("Error with getting Id from URL: %s", err.Error())
+		response.Abort(ctx, http.StatusBadRequest, fmt.Sprintf("Error with getting Id from URL: %s", err.Error()))
+		slog.DebugContext(ctx, "Error with getting Id from URL", "error", err)
 		return
 	}
 
@@ -194,12 +163,9 @@ func (h *Handler) PublishPodcast(ctx *gin.Context) {
 	defer cancel()
 	resp, err := h.ClientPodcasts.PublishPodcast(nestedctx, &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "StatusInternalServerError",
-			"message": fmt.Sprintf("Error with request to podcasts service: %s", err.Error()),
-		})
-		log.Printf("Error with request to podcasts service: %s", err.Error())
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error with request to podcasts service", "error", err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, resp)
+	response.JSON(ctx, http.StatusAccepted, resp)
 }
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"api_gateway/api/response"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+// errVersionUnknown is returned by lookups that give up before finding the
+// current version of a resource, which is then answered with 503.
+var errVersionUnknown = errors.New("current version cannot be determined")
+
+// ifMatch checks the request's If-Match header against the current version
+// of the resource, loaded by current only when the header is present. It
+// writes 412 and returns false when the client's version is stale. current
+// returns a nil message when the resource does not exist.
+//
+// The check and the update that follows are separate backend calls, so it
+// narrows the window for lost updates rather than closing it.
+func ifMatch(ctx *gin.Context, current func(context.Context) (proto.Message, error)) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	msg, err := current(tctx)
+	if errors.Is(err, errVersionUnknown) {
+		response.Abort(ctx, http.StatusServiceUnavailable, "cannot check If-Match: "+err.Error())
+		slog.WarnContext(ctx, "If-Match precondition cannot be checked", "error", err)
+		return false
+	}
+	if err != nil {
+		response.AbortWithGRPCError(ctx, err)
+		slog.ErrorContext(ctx, "Error while loading current version for If-Match", "error", err)
+		return false
+	}
+	if msg == nil || !response.IfMatchAny(header, msg) {
+		response.Abort(ctx, http.StatusPreconditionFailed, "resource has changed since it was read")
+		slog.DebugContext(ctx, "If-Match precondition failed", "if_match", header)
+		return false
+	}
+	return true
+}
@@ -1,23 +1,19 @@
 package handler
 
 import (
+	"api_gateway/api/response"
 	pb "api_gateway/genproto/user_interactions"
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/pkg/errors"
 )
 
 func (h *Handler) LikeEpisodeOfPodcast(c *gin.Context) {
 	var interaction pb.InteractEpisode
-	err := c.ShouldBind(&interaction)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid data").Error()})
-		log.Println(err)
+	if !bind(c, &interaction) {
 		return
 	}
 
@@ -26,22 +22,17 @@ func (h *Handler) LikeEpisodeOfPodcast(c *gin.Context) {
 
 	id, err := h.ClientUserInteractions.LikeEpisodeOfPodcast(ctx, &interaction)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to like episode").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "LikeEpisodeOfPodcast failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"New Interaction ID": id})
+	response.JSON(c, http.StatusOK, gin.H{"New Interaction ID": id})
 }
 
 func (h *Handler) DeleteLikeFromEpisodeOfPodcast(c *gin.Context) {
 	var ids pb.DeleteLike
-	err := c.ShouldBind(&ids)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid data").Error()})
-		log.Println(err)
+	if !bind(c, &ids) {
 		return
 	}
 
@@ -50,22 +41,17 @@ func (h *Handler) DeleteLikeFromEpisodeOfPodcast(c *gin.Context) {
 
 	success, err := h.ClientUserInteractions.DeleteLikeFromEpisodeOfPodcast(ctx, &ids)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to dislike episode").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "DeleteLikeFromEpisodeOfPodcast failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Successful": success.Success})
+	response.JSON(c, http.StatusOK, gin.H{"Successful": success.Success})
 }
 
 func (h *Handler) ListenEpisodeOfPodcast(c *gin.Context) {
 	var interaction pb.InteractEpisode
-	err := c.ShouldBind(&interaction)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid data").Error()})
-		log.Println(err)
+	if !bind(c, &interaction) {
 		return
 	}
 
@@ -74,11 +60,10 @@ func (h *Handler) ListenEpisodeOfPodcast(c *gin.Context) {
 
 	id, err := h.ClientUserInteractions.ListenEpisodeOfPodcast(ctx, &interaction)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to listen to episode").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "ListenEpisodeOfPodcast failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"New Interaction ID": id})
+	response.JSON(c, http.StatusOK, gin.H{"New Interaction ID": id})
 }
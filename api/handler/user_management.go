@@ -1,9 +1,10 @@
 package handler
 
 import (
+	"api_gateway/api/response"
 	pb "api_gateway/genproto/user"
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -16,9 +17,8 @@ func (h *Handler) GetUserByID(c *gin.Context) {
 	id := c.Param("id")
 	_, err := uuid.Parse(id)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid user id").Error()})
-		log.Println(err)
+		response.Abort(c, http.StatusBadRequest, errors.Wrap(err, "invalid user id").Error())
+		slog.DebugContext(c, "invalid user id", "error", err)
 		return
 	}
 
@@ -27,31 +27,25 @@ func (h *Handler) GetUserByID(c *gin.Context) {
 
 	user, err := h.ClientUserManagement.GetUserByID(ctx, &pb.ID{Id: id})
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to get user").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "GetUserByID failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"User": user})
+	response.JSON(c, http.StatusOK, gin.H{"User": user})
 }
 
 func (h *Handler) UpdateUser(c *gin.Context) {
 	id := c.Param("id")
 	_, err := uuid.Parse(id)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid user id").Error()})
-		log.Println(err)
+		response.Abort(c, http.StatusBadRequest, errors.Wrap(err, "invalid user id").Error())
+		slog.DebugContext(c, "invalid user id", "error", err)
 		return
 	}
 
 	var user pb.User
-	err = c.ShouldBind(&user)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid data").Error()})
-		log.Println(err)
+	if !bind(c, &user) {
 		return
 	}
 
@@ -60,22 +54,20 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 
 	_, err = h.ClientUserManagement.UpdateUser(ctx, &user)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to update user").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "UpdateUser failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusNoContent, "User updated successfully")
+	response.JSON(c, http.StatusNoContent, "User updated successfully")
 }
 
 func (h *Handler) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
 	_, err := uuid.Parse(id)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid user id").Error()})
-		log.Println(err)
+		response.Abort(c, http.StatusBadRequest, errors.Wrap(err, "invalid user id").Error())
+		slog.DebugContext(c, "invalid user id", "error", err)
 		return
 	}
 
@@ -84,22 +76,20 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 
 	_, err = h.ClientUserManagement.DeleteUser(ctx, &pb.ID{Id: id})
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to delete user").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "DeleteUser failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusNoContent, "User deleted successfully")
+	response.JSON(c, http.StatusNoContent, "User deleted successfully")
 }
 
 func (h *Handler) GetUserProfile(c *gin.Context) {
 	id := c.Param("id")
 	_, err := uuid.Parse(id)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid user id").Error()})
-		log.Println(err)
+		response.Abort(c, http.StatusBadRequest, errors.Wrap(err, "invalid user id").Error())
+		slog.DebugContext(c, "invalid user id", "error", err)
 		return
 	}
 
@@ -108,31 +98,25 @@ func (h *Handler) GetUserProfile(c *gin.Context) {
 
 	profile, err := h.ClientUserManagement.GetUserProfile(ctx, &pb.ID{Id: id})
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to get user profile").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "GetUserProfile failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"User Profile": profile})
+	response.JSON(c, http.StatusOK, gin.H{"User Profile": profile})
 }
 
 func (h *Handler) UpdateUserProfile(c *gin.Context) {
 	var profile pb.Profile
-	err := c.BindJSON(&profile)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid data").Error()})
-		log.Println(err)
+	if !bind(c, &profile) {
 		return
 	}
 
 	id := c.Param("id")
-	_, err = uuid.Parse(id)
+	_, err := uuid.Parse(id)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": errors.Wrap(err, "invalid user id").Error()})
-		log.Println(err)
+		response.Abort(c, http.StatusBadRequest, errors.Wrap(err, "invalid user id").Error())
+		slog.DebugContext(c, "invalid user id", "error", err)
 		return
 	}
 
@@ -143,11 +127,10 @@ func (h *Handler) UpdateUserProfile(c *gin.Context) {
 
 	_, err = h.ClientUserManagement.UpdateUserProfile(ctx, &profile)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			gin.H{"error": errors.Wrap(err, "failed to update user profile").Error()})
-		log.Println(err)
+		response.AbortWithGRPCError(c, err)
+		slog.ErrorContext(c, "UpdateUserProfile failed", "error", err)
 		return
 	}
 
-	c.JSON(http.StatusNoContent, "User profile deleted successfully")
+	response.JSON(c, http.StatusNoContent, "User profile deleted successfully")
 }
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"api_gateway/api/response"
+	"api_gateway/api/validate"
+	pbcol "api_gateway/genproto/collaborations"
+	pbc "api_gateway/genproto/comments"
+	pbm "api_gateway/genproto/episode_metadata"
+	pbe "api_gateway/genproto/episodes"
+	pbp "api_gateway/genproto/podcasts"
+	pbu "api_gateway/genproto/user"
+	pbi "api_gateway/genproto/user_interactions"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+// The values the backends accept for the string fields the protos leave
+// open.
+var (
+	podcastStatuses   = []string{"draft", "published"}
+	collaboratorRoles = []string{"owner", "editor", "viewer"}
+	invitationAnswers = []string{"accepted", "declined"}
+	profileRoles      = []string{"listener", "podcaster"}
+	interactionTypes  = []string{"like", "listen"}
+)
+
+// avatarImageMax bounds the decoded avatar of a profile. Profiles are sent
+// within BODY_LIMIT_DEFAULT (1 MiB by default), and an avatar this size
+// stays under it even base64 encoded in JSON, so a larger one is reported
+// on its field rather than refused as a whole with 413.
+const avatarImageMax = 512 << 10
+
+// requestRules are checked on every request body before it reaches a
+// backend. Fields filled in from the path are left out: they are checked
+// where they are parsed.
+var requestRules = validate.Set{}.
+	With(&pbp.PodcastCreate{}, validate.Fields{
+		"user_id":     {validate.Required(), validate.UUID()},
+		"title":       {validate.Required(), validate.Length(1, 200)},
+		"description": {validate.Length(0, 5000)},
+		"status":      {validate.OneOf(podcastStatuses...)},
+	}).
+	With(&pbp.PodcastUpdate{}, validate.Fields{
+		"user_id":     {validate.UUID()},
+		"title":       {validate.Length(1, 200)},
+		"description": {validate.Length(0, 5000)},
+		"status":      {validate.OneOf(podcastStatuses...)},
+	}).
+	With(&pbe.EpisodeCreate{}, validate.Fields{
+		"user_id":     {validate.Required(), validate.UUID()},
+		"title":       {validate.Required(), validate.Length(1, 200)},
+		"description": {validate.Length(0, 5000)},
+		"duration":    {validate.Range(0, 24*60*60)},
+		"genre":       {validate.Length(0, 50)},
+		"tags":        {validate.MaxItems(10), validate.Each(validate.Required(), validate.Length(1, 30))},
+	}).
+	With(&pbc.CreateComment{}, validate.Fields{
+		"user_id": {validate.Required(), validate.UUID()},
+		"content": {validate.Required(), validate.Length(1, 2000)},
+	}).
+	With(&pbcol.CreateInvite{}, validate.Fields{
+		"podcast_id": {validate.Required(), validate.UUID()},
+		"inviter_id": {validate.Required(), validate.UUID()},
+		"invitee_id": {validate.Required(), validate.UUID()},
+	}).
+	With(&pbcol.CreateCollaboration{}, validate.Fields{
+		"status":     {validate.Required(), validate.OneOf(invitationAnswers...)},
+		"podcast_id": {validate.Required(), validate.UUID()},
+		"user_id":    {validate.Required(), validate.UUID()},
+	}).
+	With(&pbcol.UpdateCollaborator{}, validate.Fields{
+		"role": {validate.Required(), validate.OneOf(collaboratorRoles...)},
+	}).
+	With(&pbu.User{}, validate.Fields{
+		"username": {validate.Length(3, 50)},
+		"email":    {validate.Email()},
+		"password": {validate.Length(8, 128)},
+	}).
+	With(&pbu.Profile{}, validate.Fields{
+		"full_name":    {validate.Length(1, 100)},
+		"bio":          {validate.Length(0, 1000)},
+		"role":         {validate.OneOf(profileRoles...)},
+		"location":     {validate.Length(0, 100)},
+		"avatar_image": {validate.Length(0, avatarImageMax)},
+		"website":      {validate.URL(), validate.Length(0, 2048)},
+	}).
+	With(&pbi.InteractEpisode{}, validate.Fields{
+		"user_id":          {validate.Required(), validate.UUID()},
+		"podcast_id":       {validate.Required(), validate.UUID()},
+		"episode_id":       {validate.Required(), validate.UUID()},
+		"interaction_type": {validate.OneOf(interactionTypes...)},
+	}).
+	With(&pbi.DeleteLike{}, validate.Fields{
+		"user_id":    {validate.Required(), validate.UUID()},
+		"podcast_id": {validate.Required(), validate.UUID()},
+		"episode_id": {validate.Required(), validate.UUID()},
+	}).
+	With(&pbm.Title{}, validate.Fields{
+		"Episode_title": {validate.Required(), validate.Length(1, 200)},
+	})
+
+// valid checks msg against requestRules. When it breaks any, it writes a
+// validation problem listing every offending field and returns false.
+func valid(ctx *gin.Context, msg proto.Message) bool {
+	violations := requestRules.Check(msg)
+	if len(violations) == 0 {
+		return true
+	}
+	response.AbortWithViolations(ctx, "request is invalid", violations)
+	slog.DebugContext(ctx, "request is invalid", "violations", violations)
+	return false
+}
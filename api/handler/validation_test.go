@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"api_gateway/config"
+	pbcol "api_gateway/genproto/collaborations"
+	pbp "api_gateway/genproto/podcasts"
+	pbu "api_gateway/genproto/user"
+	pbi "api_gateway/genproto/user_interactions"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const testID = "5f0c5e4e-8f4a-4d6c-9d4b-2f9e0c3e6a11"
+
+// violated returns the fields of msg that break requestRules.
+func violated(msg proto.Message) []string {
+	var fields []string
+	for _, v := range requestRules.Check(msg) {
+		fields = append(fields, v.Field)
+	}
+	return fields
+}
+
+func TestEnumRules(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		valid []proto.Message
+		bad   proto.Message
+		field string
+	}{
+		{
+			name: "podcast status",
+			valid: []proto.Message{
+				&pbp.PodcastCreate{UserId: testID, Title: "t", Status: "draft"},
+				&pbp.PodcastCreate{UserId: testID, Title: "t", Status: "published"},
+				&pbp.PodcastCreate{UserId: testID, Title: "t"},
+			},
+			bad:   &pbp.PodcastCreate{UserId: testID, Title: "t", Status: "archived"},
+			field: "status",
+		},
+		{
+			name: "podcast update status",
+			valid: []proto.Message{
+				&pbp.PodcastUpdate{Status: "published"},
+				&pbp.PodcastUpdate{},
+			},
+			bad:   &pbp.PodcastUpdate{Status: "Published"},
+			field: "status",
+		},
+		{
+			name: "invitation answer",
+			valid: []proto.Message{
+				&pbcol.CreateCollaboration{Status: "accepted", PodcastId: testID, UserId: testID},
+				&pbcol.CreateCollaboration{Status: "declined", PodcastId: testID, UserId: testID},
+			},
+			bad:   &pbcol.CreateCollaboration{Status: "maybe", PodcastId: testID, UserId: testID},
+			field: "status",
+		},
+		{
+			name: "collaborator role",
+			valid: []proto.Message{
+				&pbcol.UpdateCollaborator{Role: "owner"},
+				&pbcol.UpdateCollaborator{Role: "editor"},
+				&pbcol.UpdateCollaborator{Role: "viewer"},
+			},
+			bad:   &pbcol.UpdateCollaborator{Role: "admin"},
+			field: "role",
+		},
+		{
+			name: "profile role",
+			valid: []proto.Message{
+				&pbu.Profile{Role: "listener"},
+				&pbu.Profile{Role: "podcaster"},
+				&pbu.Profile{},
+			},
+			bad:   &pbu.Profile{Role: "owner"},
+			field: "role",
+		},
+		{
+			name: "interaction type",
+			valid: []proto.Message{
+				&pbi.InteractEpisode{UserId: testID, PodcastId: testID, EpisodeId: testID, InteractionType: "like"},
+				&pbi.InteractEpisode{UserId: testID, PodcastId: testID, EpisodeId: testID, InteractionType: "listen"},
+			},
+			bad:   &pbi.InteractEpisode{UserId: testID, PodcastId: testID, EpisodeId: testID, InteractionType: "share"},
+			field: "interaction_type",
+		},
+	} {
+		for _, msg := range tc.valid {
+			if fields := violated(msg); len(fields) > 0 {
+				t.Errorf("%s: %v rejected on %v", tc.name, msg, fields)
+			}
+		}
+		if fields := violated(tc.bad); len(fields) != 1 || fields[0] != tc.field {
+			t.Errorf("%s: %v violates %v, want [%s]", tc.name, tc.bad, fields, tc.field)
+		}
+	}
+}
+
+// TestAvatarRuleUnderBodyLimit checks that the largest valid profile fits
+// in the default body limit, so an oversized avatar is reported by its rule
+// rather than by the body limit.
+func TestAvatarRuleUnderBodyLimit(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Load()
+	os.Chdir(wd)
+
+	profile := &pbu.Profile{
+		UserId:      testID,
+		FullName:    strings.Repeat("n", 100),
+		Bio:         strings.Repeat("b", 1000),
+		Role:        "podcaster",
+		Location:    strings.Repeat("l", 100),
+		AvatarImage: make([]byte, avatarImageMax),
+		Website:     "https://example.com/" + strings.Repeat("w", 2000),
+	}
+	if fields := violated(profile); len(fields) > 0 {
+		t.Fatalf("largest valid profile rejected on %v", fields)
+	}
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(body)) > cfg.BODY_LIMIT_DEFAULT {
+		t.Errorf("largest valid profile is %d bytes of JSON, over BODY_LIMIT_DEFAULT %d", len(body), cfg.BODY_LIMIT_DEFAULT)
+	}
+
+	profile.AvatarImage = make([]byte, avatarImageMax+1)
+	if fields := violated(profile); len(fields) != 1 || fields[0] != "avatar_image" {
+		t.Errorf("oversized avatar violates %v, want [avatar_image]", fields)
+	}
+}
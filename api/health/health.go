@@ -0,0 +1,167 @@
+package health
+
+import (
+	"api_gateway/pkg"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+
+	MethodHealth   = "grpc.health.v1"
+	MethodValidate = "validate"
+)
+
+// Dependency is the outcome of checking a single backend.
+type Dependency struct {
+	Status    string `json:"status"`
+	Method    string `json:"method"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the body of the readiness endpoint.
+type Report struct {
+	Status       string                `json:"status"`
+	CheckedAt    time.Time             `json:"checked_at"`
+	Dependencies map[string]Dependency `json:"dependencies,omitempty"`
+}
+
+// Probe answers the orchestrator's liveness and readiness checks. Readiness
+// requires every configured backend to be reachable and fails as soon as
+// Drain is called at the start of a shutdown.
+type Probe struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewProbe returns a probe that gives each backend timeout to answer and
+// reuses a readiness report for cacheTTL, so frequent probes do not load the
+// backends.
+func NewProbe(timeout, cacheTTL time.Duration) *Probe {
+	return &Probe{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Drain makes the readiness check fail so no new traffic is routed to the
+// gateway while in-flight requests finish.
+func (p *Probe) Drain() {
+	p.draining.Store(true)
+}
+
+func (p *Probe) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": StatusOK})
+}
+
+func (p *Probe) Ready(ctx *gin.Context) {
+	if p.draining.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, Report{
+			Status:    StatusDraining,
+			CheckedAt: time.Now().UTC(),
+		})
+		return
+	}
+
+	report := p.check(ctx)
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, report)
+}
+
+// check returns the cached report while it is fresh. Otherwise it checks all
+// backends in parallel; concurrent callers wait for that check instead of
+// starting their own.
+func (p *Probe) check(ctx context.Context) Report {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.report != nil && time.Since(p.report.CheckedAt) < p.cacheTTL {
+		return *p.report
+	}
+
+	backends := pkg.Backends()
+	results := make([]Dependency, len(backends))
+
+	var wg sync.WaitGroup
+	for i, backend := range backends {
+		wg.Add(1)
+		go func(i int, backend pkg.Backend) {
+			defer wg.Done()
+			results[i] = p.checkBackend(context.WithoutCancel(ctx), backend)
+		}(i, backend)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:       StatusOK,
+		CheckedAt:    time.Now().UTC(),
+		Dependencies: make(map[string]Dependency, len(backends)),
+	}
+	for i, backend := range backends {
+		report.Dependencies[backend.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	p.report = &report
+
+	return report
+}
+
+// checkBackend asks the backend through the gRPC health protocol and falls
+// back to its Validate RPC when the protocol is not implemented.
+func (p *Probe) checkBackend(ctx context.Context, backend pkg.Backend) Dependency {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	dep := Dependency{Status: StatusOK, Method: MethodHealth}
+
+	resp, err := healthpb.NewHealthClient(backend.Conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	switch {
+	case status.Code(err) == codes.Unimplemented && backend.Validate != nil:
+		dep.Method = MethodValidate
+		if err := backend.Validate(ctx, backend.Conn); !reachable(err) {
+			dep.Status = StatusUnavailable
+			dep.Error = status.Convert(err).Message()
+		}
+	case err != nil:
+		dep.Status = StatusUnavailable
+		dep.Error = status.Convert(err).Message()
+	case resp.GetStatus() != healthpb.HealthCheckResponse_SERVING:
+		dep.Status = StatusUnavailable
+		dep.Error = resp.GetStatus().String()
+	}
+	dep.LatencyMs = time.Since(start).Milliseconds()
+
+	return dep
+}
+
+// reachable reports whether err shows the backend answered, even if it
+// rejected the probe request itself.
+func reachable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Unknown, codes.Internal:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StrictJSONKey is set on requests whose JSON body must not contain fields
+// unknown to the target message.
+const StrictJSONKey = "strict_json"
+
+// BodyLimits configures how large and what kind of request bodies routes
+// accept.
+type BodyLimits struct {
+	// Default is the largest body, in bytes, accepted by most routes.
+	Default int64
+	// Upload replaces Default on UploadRoutes.
+	Upload       int64
+	UploadRoutes []string
+	// StrictRoutes reject JSON bodies with unknown fields.
+	StrictRoutes []string
+	// ContentTypes are the media types a request body may have.
+	ContentTypes []string
+	// ReadTimeout bounds reading the body of most routes. UploadTimeout
+	// replaces it on UploadRoutes, where it also bounds writing the
+	// response, as the server's write timeout runs from the headers.
+	ReadTimeout   time.Duration
+	UploadTimeout time.Duration
+}
+
+// BodyLimit rejects bodies larger than the route's limit with 413 and bodies
+// of an unsupported media type with 415. The limit is enforced while the
+// handler reads, so a body without Content-Length cannot exceed it either,
+// and so is the route's read deadline.
+func BodyLimit(limits BodyLimits) gin.HandlerFunc {
+	uploads := routeSet(limits.UploadRoutes)
+	strict := routeSet(limits.StrictRoutes)
+	types := make(map[string]bool, len(limits.ContentTypes))
+	for _, t := range limits.ContentTypes {
+		types[strings.ToLower(t)] = true
+	}
+
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		if strict[route] {
+			ctx.Set(StrictJSONKey, true)
+		}
+		if !hasBody(ctx.Request) {
+			ctx.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+		if err != nil || !types[mediaType] {
+			response.Abort(ctx, http.StatusUnsupportedMediaType,
+				"request body must be one of: "+strings.Join(limits.ContentTypes, ", "))
+			return
+		}
+
+		limit, timeout := limits.Default, limits.ReadTimeout
+		if uploads[route] {
+			limit, timeout = limits.Upload, limits.UploadTimeout
+		}
+		if ctx.Request.ContentLength > limit {
+			response.Abort(ctx, http.StatusRequestEntityTooLarge,
+				"request body exceeds "+strconv.FormatInt(limit, 10)+" bytes")
+			return
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		if timeout > 0 {
+			setDeadlines(ctx, time.Now().Add(timeout), uploads[route])
+		}
+
+		ctx.Next()
+	}
+}
+
+// setDeadlines sets the read deadline of the connection and, when write is
+// set, the write deadline. Writers that do not support deadlines keep the
+// server's.
+func setDeadlines(ctx *gin.Context, deadline time.Time, write bool) {
+	rc := http.NewResponseController(ctx.Writer)
+	err := rc.SetReadDeadline(deadline)
+	if err == nil && write {
+		err = rc.SetWriteDeadline(deadline)
+	}
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.WarnContext(ctx, "cannot set connection deadline", "error", err)
+	}
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+func routeSet(routes []string) map[string]bool {
+	set := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		set[route] = true
+	}
+	return set
+}
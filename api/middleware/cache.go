@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_response_cache_lookups_total",
+		Help: "Response cache lookups, by route template and result (hit, miss or bypass).",
+	}, []string{"route", "result"})
+
+	cacheInvalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_response_cache_invalidated_total",
+		Help: "Cached responses dropped by mutations, by the mutating route template.",
+	}, []string{"route"})
+)
+
+// CachePolicy describes how the responses of a route are cached.
+type CachePolicy struct {
+	TTL time.Duration
+	// PerUser keys responses by the caller too, for personalized routes.
+	PerUser bool
+	Tags    []string
+}
+
+// CacheRules configures the response cache by route template.
+type CacheRules struct {
+	// Routes are the GET routes whose successful responses are cached.
+	Routes map[string]CachePolicy
+	// Invalidations maps mutations, as "METHOD route", to the tags of the
+	// cached responses they make stale.
+	Invalidations map[string][]string
+}
+
+// Cache serves GET responses of the configured routes from store until
+// their TTL runs out, announcing their freshness with Cache-Control and
+// Age. Successful mutations drop the responses they invalidate. Clients
+// sending Cache-Control: no-cache bypass the lookup and refresh the entry.
+func Cache(store CacheStore, rules CacheRules) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+
+		if ctx.Request.Method != http.MethodGet {
+			tags, ok := rules.Invalidations[ctx.Request.Method+" "+route]
+			ctx.Next()
+			if status := ctx.Writer.Status(); ok && status >= 200 && status < 300 {
+				dropped := store.Invalidate(tags...)
+				cacheInvalidations.WithLabelValues(route).Add(float64(dropped))
+				slog.DebugContext(ctx, "response cache invalidated", "tags", tags, "dropped", dropped)
+			}
+			return
+		}
+
+		policy, ok := rules.Routes[route]
+		if !ok || policy.TTL <= 0 {
+			ctx.Next()
+			return
+		}
+
+		key := cacheKey(ctx, policy)
+		now := time.Now()
+		result := "bypass"
+		if !noCache(ctx.Request) {
+			if cached, ok := store.Get(key, now); ok {
+				cacheLookups.WithLabelValues(route, "hit").Inc()
+				setCacheHeaders(ctx.Writer.Header(), policy, "HIT", now.Sub(cached.Stored))
+				if fresh(ctx.Request, cached) {
+					for name, values := range cached.Header {
+						ctx.Writer.Header()[name] = values
+					}
+					ctx.Status(http.StatusNotModified)
+					ctx.Abort()
+					return
+				}
+				replay(ctx, cached)
+				return
+			}
+			result = "miss"
+		}
+		cacheLookups.WithLabelValues(route, result).Inc()
+
+		// The stored headers are taken as the handler wrote them, before
+		// writers further out, such as Compress, adapt them to this client.
+		var header http.Header
+		recorder := &bodyRecorder{ResponseWriter: ctx.Writer}
+		recorder.beforeWrite = func() {
+			header = replayedHeader(recorder.Header())
+			if recorder.Status() == http.StatusOK {
+				setCacheHeaders(recorder.Header(), policy, "MISS", 0)
+			}
+		}
+		ctx.Writer = recorder
+		ctx.Next()
+		ctx.Writer = recorder.ResponseWriter
+
+		if recorder.Status() == http.StatusOK && !recorder.overflow {
+			if header == nil {
+				header = replayedHeader(recorder.Header())
+			}
+			store.Set(key, &CachedResponse{
+				Status:      http.StatusOK,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+				Header:      header,
+				Stored:      now,
+				Expires:     now.Add(policy.TTL),
+				Tags:        policy.Tags,
+			})
+		}
+	}
+}
+
+// cacheKey identifies a response by route, path parameters, normalized
+// query, negotiated format and, for personalized routes, the caller.
+func cacheKey(ctx *gin.Context, policy CachePolicy) string {
+	var b strings.Builder
+	b.WriteString(ctx.FullPath())
+	for _, param := range ctx.Params {
+		b.WriteString("|" + param.Key + "=" + param.Value)
+	}
+	b.WriteString("?" + normalizedQuery(ctx.Request.URL.Query()))
+	b.WriteString("|format=" + ctx.GetString(response.FormatKey))
+	if policy.PerUser {
+		caller, _ := callerOf(ctx)
+		b.WriteString("|user=" + caller.UserID)
+	}
+	return b.String()
+}
+
+// normalizedQuery drops empty parameters and sorts the rest by name, so
+// equivalent queries share an entry.
+func normalizedQuery(query url.Values) string {
+	for name, values := range query {
+		kept := values[:0]
+		for _, v := range values {
+			if v != "" {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 {
+			delete(query, name)
+		} else {
+			query[name] = kept
+		}
+	}
+	return query.Encode()
+}
+
+func noCache(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") ||
+		r.Header.Get("Pragma") == "no-cache"
+}
+
+func setCacheHeaders(header http.Header, policy CachePolicy, result string, age time.Duration) {
+	header.Set("Cache-Control", "private, max-age="+strconv.Itoa(int(policy.TTL.Seconds())))
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	header.Set("X-Cache", result)
+}
+
+// fresh reports whether the conditional headers of r show the client
+// already has the cached response, as notModified decides for a handler.
+func fresh(r *http.Request, cached *CachedResponse) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		etag := cached.Header.Get("ETag")
+		return etag != "" && response.IfNoneMatch(header, etag)
+	}
+	modified, err := http.ParseTime(cached.Header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.After(since)
+}
+
+// replayedHeaders describe the representation or its neighbours and are
+// kept with stored responses, unlike per-request ones such as X-Request-Id.
+var replayedHeaders = []string{"ETag", "Last-Modified", "Link", "Location"}
+
+func replayedHeader(header http.Header) http.Header {
+	kept := http.Header{}
+	for _, name := range replayedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			kept[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+	return kept
+}
+
+// replay writes a stored response and stops the handler chain. Stored
+// headers replace those set so far, which they already include.
+func replay(ctx *gin.Context, resp *CachedResponse) {
+	for name, values := range resp.Header {
+		ctx.Writer.Header()[name] = values
+	}
+	if resp.ContentType == "" && len(resp.Body) == 0 {
+		ctx.Status(resp.Status)
+		ctx.Abort()
+		return
+	}
+	ctx.Data(resp.Status, resp.ContentType, resp.Body)
+	ctx.Abort()
+}
+
+// maxRecordedBody bounds the body kept by bodyRecorder; larger responses
+// are passed through without being kept.
+const maxRecordedBody = 4 << 20
+
+// bodyRecorder copies the body as it is written.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+	// beforeWrite, when set, runs once before the headers go out.
+	beforeWrite func()
+	started     bool
+}
+
+func (w *bodyRecorder) Write(p []byte) (int, error) {
+	w.start()
+	if !w.overflow {
+		if w.body.Len()+len(p) > maxRecordedBody {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *bodyRecorder) WriteHeaderNow() {
+	w.start()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *bodyRecorder) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if w.beforeWrite != nil {
+		w.beforeWrite()
+	}
+}
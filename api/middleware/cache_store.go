@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response kept by the response cache.
+type CachedResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+	// Header holds the replayedHeaders the response was sent with.
+	Header  http.Header
+	Stored  time.Time
+	Expires time.Time
+	// Tags group entries so mutations can invalidate them together.
+	Tags []string
+}
+
+// CacheStore keeps cached responses. Implementations must be safe for
+// concurrent use.
+type CacheStore interface {
+	// Get returns the unexpired response stored under key.
+	Get(key string, now time.Time) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	// Invalidate drops every response carrying one of tags and returns how
+	// many were dropped.
+	Invalidate(tags ...string) int
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+	size int64
+}
+
+// LRUCache is an in-memory CacheStore bounded by the total size of the
+// cached bodies. The least recently used responses are evicted first;
+// expired ones are dropped when next looked up.
+type LRUCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+	tagged   map[string]map[string]struct{}
+}
+
+// NewLRUCache returns a cache holding at most maxBytes of keys and bodies.
+func NewLRUCache(maxBytes int64) *LRUCache {
+	return &LRUCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+		tagged:   map[string]map[string]struct{}{},
+	}
+}
+
+func (c *LRUCache) Get(key string, now time.Time) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !now.Before(entry.resp.Expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.resp, true
+}
+
+func (c *LRUCache) Set(key string, resp *CachedResponse) {
+	size := int64(len(key) + len(resp.Body))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	for c.size+size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, resp: resp, size: size})
+	c.size += size
+	for _, tag := range resp.Tags {
+		if c.tagged[tag] == nil {
+			c.tagged[tag] = map[string]struct{}{}
+		}
+		c.tagged[tag][key] = struct{}{}
+	}
+}
+
+func (c *LRUCache) Invalidate(tags ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dropped := 0
+	for _, tag := range tags {
+		for key := range c.tagged[tag] {
+			if elem, ok := c.entries[key]; ok {
+				c.remove(elem)
+				dropped++
+			}
+		}
+	}
+	return dropped
+}
+
+func (c *LRUCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	for _, tag := range entry.resp.Tags {
+		delete(c.tagged[tag], entry.key)
+		if len(c.tagged[tag]) == 0 {
+			delete(c.tagged, tag)
+		}
+	}
+}
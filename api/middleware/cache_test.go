@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	pb "api_gateway/genproto/podcasts"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCacheWithCompression checks that a cached response is replayed with
+// the entity tag of the representation each client gets, whichever client
+// filled the cache, and that cached responses are revalidated with 304.
+func TestCacheWithCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	runs := 0
+	r := gin.New()
+	r.Use(
+		Compress(CompressionConfig{MinSize: 1, GzipLevel: 5, BrotliLevel: 4}),
+		Cache(NewLRUCache(1<<20), CacheRules{Routes: map[string]CachePolicy{
+			"/discover/trending": {TTL: time.Minute},
+		}}),
+	)
+	r.GET("/discover/trending", func(ctx *gin.Context) {
+		runs++
+		response.ConditionalJSON(ctx, http.StatusOK, &pb.Podcast{
+			Id:    "5f0c5e4e-8f4a-4d6c-9d4b-2f9e0c3e6a11",
+			Title: strings.Repeat("trending ", 20),
+		})
+	})
+	etag := response.ETag(&pb.Podcast{
+		Id:    "5f0c5e4e-8f4a-4d6c-9d4b-2f9e0c3e6a11",
+		Title: strings.Repeat("trending ", 20),
+	}, response.MIMEJSON)
+	encoded := func(encoding string) string {
+		return strings.TrimSuffix(etag, `"`) + "+" + encoding + `"`
+	}
+
+	for _, tc := range []struct {
+		name, acceptEncoding, ifNoneMatch string
+		status                            int
+		etag, cache                       string
+	}{
+		{"gzip fills the cache", "gzip", "", http.StatusOK, encoded("gzip"), "MISS"},
+		{"identity", "", "", http.StatusOK, etag, "HIT"},
+		{"brotli", "br", "", http.StatusOK, encoded("br"), "HIT"},
+		{"identity revalidates", "", etag, http.StatusNotModified, etag, "HIT"},
+		{"gzip revalidates", "gzip", encoded("gzip"), http.StatusNotModified, encoded("gzip"), "HIT"},
+		{"brotli with a stale tag", "br", `"stale"`, http.StatusOK, encoded("br"), "HIT"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/discover/trending", nil)
+		if tc.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		}
+		if tc.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", tc.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.status)
+		}
+		if got := w.Header().Get("ETag"); got != tc.etag {
+			t.Errorf("%s: ETag %s, want %s", tc.name, got, tc.etag)
+		}
+		if got := w.Header().Get("X-Cache"); got != tc.cache {
+			t.Errorf("%s: X-Cache %q, want %q", tc.name, got, tc.cache)
+		}
+		if tc.status == http.StatusNotModified && w.Body.Len() > 0 {
+			t.Errorf("%s: 304 with a body of %d bytes", tc.name, w.Body.Len())
+		}
+	}
+	if runs != 1 {
+		t.Errorf("handler ran %d times, want 1", runs)
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"api_gateway/pkg"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Coalesce lets concurrent identical reads of the given route templates
+// share their backend calls, as pkg.CoalescingInterceptor allows.
+func Coalesce(routes []string) gin.HandlerFunc {
+	coalesced := routeSet(routes)
+
+	return func(ctx *gin.Context) {
+		method := ctx.Request.Method
+		if (method == http.MethodGet || method == http.MethodHead) && coalesced[ctx.FullPath()] {
+			ctx.Request = ctx.Request.WithContext(pkg.WithCoalescing(ctx.Request.Context()))
+		}
+		ctx.Next()
+	}
+}
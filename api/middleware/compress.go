@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// CompressionConfig tunes response compression.
+type CompressionConfig struct {
+	// MinSize is the smallest body, in bytes, worth compressing.
+	MinSize     int
+	GzipLevel   int
+	BrotliLevel int
+}
+
+// compressor is the part of gzip.Writer and brotli.Writer the middleware
+// uses; both can be reset onto a new destination, which lets them be pooled.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// skippedTypes are media types that are already compressed.
+var skippedTypes = []string{
+	"audio/", "video/", "image/",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-brotli", "application/octet-stream", "application/pdf",
+	"font/woff", "font/woff2",
+}
+
+// Compress encodes responses with brotli or gzip, whichever the client
+// prefers in Accept-Encoding. Bodies smaller than MinSize, already encoded
+// bodies and already compressed media types are sent as they are.
+func Compress(cfg CompressionConfig) gin.HandlerFunc {
+	pools := map[string]*sync.Pool{
+		EncodingBrotli: {New: func() any { return brotli.NewWriterLevel(io.Discard, cfg.BrotliLevel) }},
+		EncodingGzip: {New: func() any {
+			w, err := gzip.NewWriterLevel(io.Discard, cfg.GzipLevel)
+			if err != nil {
+				w = gzip.NewWriter(io.Discard)
+			}
+			return w
+		}},
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(ctx.GetHeader("Accept-Encoding"))
+		revalidated := decodeETags(ctx.Request.Header, encoding)
+		if encoding == "" || ctx.Request.Method == http.MethodHead || ctx.GetHeader("Range") != "" {
+			ctx.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: ctx.Writer,
+			encoding:       encoding,
+			minSize:        cfg.MinSize,
+			pool:           pools[encoding],
+			revalidated:    revalidated,
+		}
+		ctx.Writer = cw
+		defer func() {
+			cw.close()
+			ctx.Writer = cw.ResponseWriter
+		}()
+
+		ctx.Next()
+	}
+}
+
+// negotiateEncoding picks brotli or gzip from an Accept-Encoding header,
+// preferring brotli when both are equally acceptable.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		candidates := []string{name}
+		if name == "*" {
+			candidates = []string{EncodingBrotli, EncodingGzip}
+		}
+		for _, c := range candidates {
+			if c != EncodingBrotli && c != EncodingGzip || q <= 0 {
+				continue
+			}
+			if q > bestQ || q == bestQ && c == EncodingBrotli {
+				best, bestQ = c, q
+			}
+		}
+	}
+	return best
+}
+
+func compressible(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	contentType = strings.ToLower(contentType)
+	if strings.HasPrefix(contentType, "image/svg+xml") {
+		return true
+	}
+	for _, t := range skippedTypes {
+		if strings.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// compressWriter holds back the start of the body until it knows whether the
+// response is large enough to compress.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+	pool     *sync.Pool
+
+	buf     []byte
+	decided bool
+	enc     compressor
+	// revalidated is set when the client's validators came from a response
+	// in this encoding, which a 304 must then name.
+	revalidated bool
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Written reports whether the handler produced a response, including one
+// still held back in the buffer.
+func (w *compressWriter) Written() bool {
+	return w.buf != nil || w.ResponseWriter.Written()
+}
+
+// WriteHeaderNow decides on the encoding first, so that headers sent
+// without a body, such as those of a 304, carry its entity tag.
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide starts compressing when the body is large enough and of a
+// compressible type, then writes out the buffered start of the body.
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	header := w.Header()
+
+	if large && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) &&
+		bodyAllowed(w.Status()) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		encodeETag(header, w.encoding)
+		w.enc = w.pool.Get().(compressor)
+		w.enc.Reset(w.ResponseWriter)
+	} else if w.revalidated && w.Status() == http.StatusNotModified {
+		encodeETag(header, w.encoding)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(io.Discard)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// encodeETag marks the strong entity tag of a response with its content
+// coding, as RFC 9110 wants a strong validator per representation.
+func encodeETag(header http.Header, encoding string) {
+	etag := header.Get("ETag")
+	if strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`) && len(etag) > 1 {
+		header.Set("ETag", strings.TrimSuffix(etag, `"`)+"+"+encoding+`"`)
+	}
+}
+
+// decodeETags strips the content coding encodeETag added from the entity
+// tags of the conditional request headers, so handlers can compare them
+// with the tags they compute. It reports whether any carried encoding.
+func decodeETags(header http.Header, encoding string) bool {
+	found := false
+	for _, name := range []string{"If-Match", "If-None-Match"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		tags := strings.Split(value, ",")
+		for i, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			for _, e := range []string{EncodingBrotli, EncodingGzip} {
+				if trimmed, ok := strings.CutSuffix(tag, "+"+e+`"`); ok {
+					tag = trimmed + `"`
+					found = found || e == encoding
+					break
+				}
+			}
+			tags[i] = tag
+		}
+		header.Set(name, strings.Join(tags, ", "))
+	}
+	return found
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig lists what browser clients on other origins may do. Origins
+// are exact ("https://player.example.com"), "*" for any origin, or carry a
+// wildcard subdomain ("https://*.example.com").
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS answers preflight requests itself, before authentication runs, since
+// browsers send them without an Authorization header, and adds the CORS
+// headers to actual requests from allowed origins.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	anyOrigin := false
+	exact := map[string]bool{}
+	var wildcards []originPattern
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "":
+		case origin == "*":
+			anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*.")
+			wildcards = append(wildcards, originPattern{scheme: scheme + "://", suffix: "." + host})
+		default:
+			exact[origin] = true
+		}
+	}
+
+	anyHeader := false
+	allowedHeaders := map[string]bool{}
+	for _, h := range cfg.AllowedHeaders {
+		h = strings.TrimSpace(h)
+		if h == "*" {
+			anyHeader = true
+		}
+		allowedHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	allowedMethods := map[string]bool{}
+	for _, m := range cfg.AllowedMethods {
+		allowedMethods[strings.ToUpper(strings.TrimSpace(m))] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	originAllowed := func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if anyOrigin || exact[origin] {
+			return true
+		}
+		for _, p := range wildcards {
+			if p.match(origin) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+
+		preflight := ctx.Request.Method == http.MethodOptions &&
+			ctx.GetHeader("Access-Control-Request-Method") != ""
+
+		if !anyOrigin || cfg.AllowCredentials {
+			ctx.Writer.Header().Add("Vary", "Origin")
+		}
+		if !originAllowed(origin) {
+			if preflight {
+				response.Abort(ctx, http.StatusForbidden, "origin "+origin+" is not allowed")
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		// A credentialed response must name the origin; "*" is rejected by
+		// browsers.
+		if anyOrigin && !cfg.AllowCredentials {
+			ctx.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			ctx.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			ctx.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposed != "" {
+				ctx.Header("Access-Control-Expose-Headers", exposed)
+			}
+			ctx.Next()
+			return
+		}
+
+		ctx.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+		ctx.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+
+		method := strings.ToUpper(ctx.GetHeader("Access-Control-Request-Method"))
+		if !allowedMethods[method] {
+			response.Abort(ctx, http.StatusForbidden, "method "+method+" is not allowed")
+			return
+		}
+
+		requested := ctx.GetHeader("Access-Control-Request-Headers")
+		if !anyHeader {
+			for _, h := range strings.Split(requested, ",") {
+				if h = strings.TrimSpace(h); h != "" && !allowedHeaders[http.CanonicalHeaderKey(h)] {
+					response.Abort(ctx, http.StatusForbidden, "header "+h+" is not allowed")
+					return
+				}
+			}
+		}
+
+		ctx.Header("Access-Control-Allow-Methods", methods)
+		if anyHeader && requested != "" {
+			ctx.Header("Access-Control-Allow-Headers", requested)
+		} else if headers != "" {
+			ctx.Header("Access-Control-Allow-Headers", headers)
+		}
+		if cfg.MaxAge > 0 {
+			ctx.Header("Access-Control-Max-Age", maxAge)
+		}
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+type originPattern struct {
+	scheme string
+	suffix string
+}
+
+// match reports whether origin is a subdomain of the pattern's host. The
+// bare host itself is not matched.
+func (p originPattern) match(origin string) bool {
+	host, ok := strings.CutPrefix(origin, p.scheme)
+	return ok && len(host) > len(p.suffix) && strings.HasSuffix(host, p.suffix)
+}
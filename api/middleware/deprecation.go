@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation describes an API version that clients should move away from.
+type Deprecation struct {
+	// Since is when the version was deprecated.
+	Since time.Time
+	// Sunset is when the version stops being served, zero if not decided.
+	Sunset time.Time
+	// Prefix is the path prefix of the deprecated version and Successor the
+	// prefix of the version replacing it, used to link each request to its
+	// equivalent.
+	Prefix    string
+	Successor string
+}
+
+// Deprecated announces the deprecation on every response with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers and a
+// successor-version link.
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	sunset := ""
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", deprecation)
+		if sunset != "" {
+			ctx.Header("Sunset", sunset)
+		}
+		if d.Successor != "" {
+			path := d.Successor + strings.TrimPrefix(ctx.Request.URL.Path, d.Prefix)
+			ctx.Writer.Header().Add("Link", "<"+path+`>; rel="successor-version"`)
+		}
+		ctx.Next()
+	}
+}
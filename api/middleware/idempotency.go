@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// Idempotency makes retries of the given routes, written as "METHOD route",
+// safe when the client sends an Idempotency-Key. The first request with a
+// key runs and its response is kept for ttl; repeats with the same body get
+// that response replayed, repeats with a different body get 422, and
+// repeats arriving while the first is still running wait for it. Keys are
+// scoped to the caller. Server errors are not kept, so they can be retried.
+func Idempotency(store IdempotencyStore, ttl time.Duration, routes []string) gin.HandlerFunc {
+	idempotent := routeSet(routes)
+
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !idempotent[ctx.Request.Method+" "+ctx.FullPath()] {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.Abort(ctx, http.StatusBadRequest,
+				IdempotencyKeyHeader+" must be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters")
+			return
+		}
+
+		fingerprint, cleanup, ok := requestFingerprint(ctx)
+		if !ok {
+			return
+		}
+		defer cleanup()
+		caller, _ := callerOf(ctx)
+		storeKey := caller.UserID + "\x00" + key
+
+		for {
+			record, reserved := store.Reserve(storeKey, fingerprint, ttl, time.Now())
+			if reserved {
+				runIdempotent(ctx, store, storeKey, record)
+				return
+			}
+			if record.Fingerprint != fingerprint {
+				response.Abort(ctx, http.StatusUnprocessableEntity,
+					IdempotencyKeyHeader+" was already used with a different request")
+				return
+			}
+
+			select {
+			case <-record.Done():
+			case <-ctx.Request.Context().Done():
+				response.Abort(ctx, response.StatusClientClosedRequest, "request cancelled")
+				return
+			}
+			if resp := record.Response; resp != nil {
+				ctx.Header(IdempotentReplayedHeader, "true")
+				replay(ctx, resp)
+				return
+			}
+			// The first request was released without a kept response;
+			// try to become the one that runs.
+		}
+	}
+}
+
+// runIdempotent serves the request holding the key and keeps its response,
+// or releases the key when the outcome must not be replayed. The release is
+// deferred so a panicking handler does not leave waiters blocked.
+func runIdempotent(ctx *gin.Context, store IdempotencyStore, key string, record *IdempotencyRecord) {
+	recorder := &bodyRecorder{ResponseWriter: ctx.Writer}
+	ctx.Writer = recorder
+
+	completed := false
+	defer func() {
+		ctx.Writer = recorder.ResponseWriter
+		if !completed {
+			store.Release(key, record)
+		}
+	}()
+
+	ctx.Next()
+
+	// A handler that returns without writing has succeeded with an empty
+	// body, which is kept like any other response so a retry does not run
+	// it again.
+	status := recorder.Status()
+	if recorder.overflow || status >= http.StatusInternalServerError ||
+		status == http.StatusTooManyRequests || (!recorder.Written() && status >= http.StatusMultipleChoices) {
+		return
+	}
+	now := time.Now()
+	store.Complete(key, record, &CachedResponse{
+		Status:      status,
+		ContentType: recorder.Header().Get("Content-Type"),
+		Body:        bytes.Clone(recorder.body.Bytes()),
+		Header:      replayedHeader(recorder.Header()),
+		Stored:      now,
+	})
+	completed = true
+}
+
+// spoolThreshold is the size above which a body read for its fingerprint
+// is kept in a temporary file rather than in memory.
+const spoolThreshold = 1 << 20
+
+// requestFingerprint hashes the method, path, negotiated response format
+// and body. The body is streamed through the hash into a buffer, or a
+// temporary file when it is large, so the handler can still read it; the
+// returned function removes that file. It writes the problem response and
+// returns false when the body cannot be read.
+func requestFingerprint(ctx *gin.Context) (string, func(), bool) {
+	h := sha256.New()
+	io.WriteString(h, ctx.Request.Method+" "+ctx.Request.URL.Path+" "+ctx.GetString(response.FormatKey)+"\x00")
+	if ctx.Request.Body == nil {
+		return hex.EncodeToString(h.Sum(nil)), func() {}, true
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(h, &buf), io.LimitReader(ctx.Request.Body, spoolThreshold+1))
+	if err == nil && n <= spoolThreshold {
+		ctx.Request.Body = io.NopCloser(&buf)
+		return hex.EncodeToString(h.Sum(nil)), func() {}, true
+	}
+
+	var spool *os.File
+	if err == nil {
+		spool, err = spoolBody(h, &buf, ctx.Request.Body)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			response.Abort(ctx, http.StatusRequestEntityTooLarge,
+				"request body exceeds "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes")
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			response.Abort(ctx, http.StatusRequestTimeout, "request body was not received in time")
+		case errors.Is(err, errSpool):
+			response.Abort(ctx, http.StatusInternalServerError, "cannot buffer request body")
+		default:
+			response.Abort(ctx, http.StatusBadRequest, "cannot read request body: "+err.Error())
+		}
+		slog.DebugContext(ctx, "cannot read request body", "error", err)
+		return "", func() {}, false
+	}
+
+	ctx.Request.Body = spool
+	return hex.EncodeToString(h.Sum(nil)), func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}, true
+}
+
+var errSpool = errors.New("cannot spool request body")
+
+// spoolBody writes head, then the rest of body through h, into a temporary
+// file positioned at its start.
+func spoolBody(h io.Writer, head io.Reader, body io.Reader) (*os.File, error) {
+	spool, err := os.CreateTemp("", "idempotency-body-*")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errSpool, err)
+	}
+	_, err = io.Copy(spool, head)
+	if err == nil {
+		_, err = io.Copy(io.MultiWriter(h, spool), body)
+	}
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, err
+	}
+	return spool, nil
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the state of an idempotency key.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string
+	// Response is the kept outcome of the first request, set once Done is
+	// closed.
+	Response *CachedResponse
+
+	done chan struct{}
+}
+
+// Done is closed once the first request using the key finished.
+func (r *IdempotencyRecord) Done() <-chan struct{} {
+	return r.done
+}
+
+// IdempotencyStore remembers idempotency keys and the responses they
+// produced. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Reserve claims key for a new request. When the key is already held,
+	// it returns the existing record and false.
+	Reserve(key, fingerprint string, ttl time.Duration, now time.Time) (*IdempotencyRecord, bool)
+	// Complete keeps resp as the outcome of the request that reserved key
+	// and got record.
+	Complete(key string, record *IdempotencyRecord, resp *CachedResponse)
+	// Release forgets key so the request can be retried, for outcomes that
+	// must not be replayed.
+	Release(key string, record *IdempotencyRecord)
+}
+
+type idempotencyEntry struct {
+	key     string
+	record  *IdempotencyRecord
+	expires time.Time
+	size    int64
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore bounded by the
+// total size of its keys and kept bodies. When it is full, the oldest keys
+// whose request has finished are forgotten first, so retries of those run
+// again.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	maxBytes  int64
+	size      int64
+	order     *list.List
+	entries   map[string]*list.Element
+	lastSweep time.Time
+	sweepEach time.Duration
+}
+
+// NewMemoryIdempotencyStore returns an empty store holding at most maxBytes
+// of keys and bodies, which drops expired keys at most once per sweepEach.
+func NewMemoryIdempotencyStore(maxBytes int64, sweepEach time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		maxBytes:  maxBytes,
+		order:     list.New(),
+		entries:   map[string]*list.Element{},
+		sweepEach: sweepEach,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key, fingerprint string, ttl time.Duration, now time.Time) (*IdempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	if elem, ok := s.entries[key]; ok {
+		entry := elem.Value.(*idempotencyEntry)
+		if now.Before(entry.expires) {
+			return entry.record, false
+		}
+		s.remove(elem)
+	}
+
+	record := &IdempotencyRecord{Fingerprint: fingerprint, done: make(chan struct{})}
+	entry := &idempotencyEntry{key: key, record: record, expires: now.Add(ttl), size: int64(len(key) + len(fingerprint))}
+	s.entries[key] = s.order.PushBack(entry)
+	s.size += entry.size
+	s.evict()
+	return record, true
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, record *IdempotencyRecord, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record.Response = resp
+	close(record.done)
+	if elem, ok := s.entries[key]; ok && elem.Value.(*idempotencyEntry).record == record {
+		elem.Value.(*idempotencyEntry).size += int64(len(resp.Body))
+		s.size += int64(len(resp.Body))
+		s.evict()
+	}
+}
+
+func (s *MemoryIdempotencyStore) Release(key string, record *IdempotencyRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok && elem.Value.(*idempotencyEntry).record == record {
+		s.remove(elem)
+	}
+	close(record.done)
+}
+
+// evict forgets the oldest finished keys until the store fits maxBytes.
+// Keys of requests still running are kept, so their duplicates still wait.
+func (s *MemoryIdempotencyStore) evict() {
+	elem := s.order.Front()
+	for s.size > s.maxBytes && elem != nil {
+		next := elem.Next()
+		select {
+		case <-elem.Value.(*idempotencyEntry).record.done:
+			s.remove(elem)
+		default:
+		}
+		elem = next
+	}
+}
+
+func (s *MemoryIdempotencyStore) remove(elem *list.Element) {
+	entry := s.order.Remove(elem).(*idempotencyEntry)
+	delete(s.entries, entry.key)
+	s.size -= entry.size
+}
+
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.sweepEach {
+		return
+	}
+	for elem := s.order.Front(); elem != nil; {
+		next := elem.Next()
+		if !now.Before(elem.Value.(*idempotencyEntry).expires) {
+			s.remove(elem)
+		}
+		elem = next
+	}
+	s.lastSweep = now
+}
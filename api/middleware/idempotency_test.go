@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestIdempotencyReplaysEmptyBody covers a route whose handler succeeds
+// without writing a body, as CreateCommentByPodcastId does: retries and
+// concurrent duplicates must not run it again.
+func TestIdempotencyReplaysEmptyBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(Idempotency(NewMemoryIdempotencyStore(1<<20, time.Minute), time.Hour, []string{"POST /podcasts/:id/comments"}))
+	r.POST("/podcasts/:id/comments", func(ctx *gin.Context) {
+		runs.Add(1)
+		<-release
+	})
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/podcasts/42/comments", strings.NewReader(`{"content":"hi"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "comment-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	responses := make([]*httptest.ResponseRecorder, 3)
+	var wg sync.WaitGroup
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = post()
+		}()
+	}
+	for runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Give the duplicate time to start waiting on the key.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	responses[2] = post()
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+	replayed := 0
+	for i, w := range responses {
+		if w.Code != http.StatusOK || w.Body.Len() != 0 {
+			t.Errorf("response %d: status %d, body %q; want 200 with no body", i, w.Code, w.Body)
+		}
+		if w.Header().Get(IdempotentReplayedHeader) == "true" {
+			replayed++
+		}
+		if ct := w.Header().Values("Content-Type"); len(ct) > 0 {
+			t.Errorf("response %d: Content-Type %q on an empty body", i, ct)
+		}
+	}
+	if replayed != 2 {
+		t.Errorf("%d responses were replayed, want 2", replayed)
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"api_gateway/pkg"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog writes one structured record per request. Server errors are
+// logged at error level and client errors at warn level; successful requests
+// are logged at info level for a sampleRate fraction of them only.
+func AccessLog(logger *slog.Logger, sampleRate float64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		reqCtx, calls := pkg.WithBackendCalls(ctx.Request.Context())
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case sampleRate < 1 && rand.Float64() >= sampleRate:
+			return
+		}
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", route),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+			slog.Int("bytes", ctx.Writer.Size()),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+		if backends := calls.Names(); len(backends) > 0 {
+			attrs = append(attrs, slog.String("backend", strings.Join(backends, ",")))
+		}
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", ctx.Errors.String()))
+		}
+
+		logger.LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
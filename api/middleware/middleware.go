@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"api_gateway/api/response"
 	"api_gateway/api/token"
+	"api_gateway/pkg"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 )
 
 func JWTMiddleware() gin.HandlerFunc {
@@ -12,28 +17,64 @@ func JWTMiddleware() gin.HandlerFunc {
 		auth := ctx.GetHeader("Authorization")
 
 		if auth == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header required",
-			})
+			response.Abort(ctx, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
 		valid, err := token.ValidateToken(auth)
 		if err != nil || !valid {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "invalid token",
-			})
+			response.Abort(ctx, http.StatusUnauthorized, "invalid token")
 			return
 		}
 
 		claims, err := token.ExtractClaims(auth)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "invalid token claims",
-			})
+			response.Abort(ctx, http.StatusUnauthorized, "invalid token claims")
 			return
 		}
 		ctx.Set("claims", claims)
+
+		caller, _ := pkg.CallerFromContext(ctx.Request.Context())
+		caller.UserID = claimString(claims, "user_id", "id", "sub")
+		caller.Roles = claimRoles(claims)
+		setCaller(ctx, caller)
+
 		ctx.Next()
 	}
 }
+
+// claimString returns the first non-empty claim among keys.
+func claimString(claims jwt.MapClaims, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := claims[key]; ok && v != nil {
+			if s := fmt.Sprint(v); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// claimRoles accepts roles issued either as a single "role" claim or as a
+// "roles" list.
+func claimRoles(claims jwt.MapClaims) []string {
+	roles := []string{}
+	switch v := claims["roles"].(type) {
+	case []interface{}:
+		for _, r := range v {
+			if s := strings.TrimSpace(fmt.Sprint(r)); s != "" {
+				roles = append(roles, s)
+			}
+		}
+	case string:
+		for _, r := range strings.Split(v, ",") {
+			if s := strings.TrimSpace(r); s != "" {
+				roles = append(roles, s)
+			}
+		}
+	}
+	if role := claimString(claims, "role"); role != "" {
+		roles = append(roles, role)
+	}
+	return roles
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Negotiate picks the format of the response body from the Accept header
+// and stores it under response.FormatKey, answering 406 when the client
+// accepts none of the formats the route offers. Routes listed in
+// noProtobuf, written as "METHOD /path", answer with a body that is not a
+// proto message and offer response.Formats less protobuf. This is decided
+// before the handler runs, so that a request is never carried out only to
+// have its response refused.
+func Negotiate(noProtobuf []string) gin.HandlerFunc {
+	jsonOnly := routeSet(noProtobuf)
+	withoutProtobuf := make([]string, 0, len(response.Formats))
+	for _, format := range response.Formats {
+		if format != response.MIMEProtobuf {
+			withoutProtobuf = append(withoutProtobuf, format)
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Writer.Header().Add("Vary", "Accept")
+
+		offered := response.Formats
+		if jsonOnly[ctx.Request.Method+" "+ctx.FullPath()] {
+			offered = withoutProtobuf
+		}
+		accept := ctx.GetHeader("Accept")
+		format, ok := response.Negotiate(accept, offered)
+		if !ok {
+			response.Abort(ctx, http.StatusNotAcceptable,
+				"response can be encoded as one of: "+strings.Join(offered, ", "))
+			slog.DebugContext(ctx, "no acceptable response format", "accept", accept)
+			return
+		}
+		ctx.Set(response.FormatKey, format)
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RouteGroupReads   = "reads"
+	RouteGroupWrites  = "writes"
+	RouteGroupUploads = "uploads"
+	// RouteGroupIP is the limit every request of a client IP counts
+	// against, authenticated or not.
+	RouteGroupIP = "ip"
+)
+
+// RateLimits configures one token bucket per route group and client.
+type RateLimits struct {
+	Reads   Limit
+	Writes  Limit
+	Uploads Limit
+	// UploadRoutes are the route templates whose POST, PUT and PATCH
+	// requests count against the uploads limit instead of the writes limit.
+	UploadRoutes []string
+}
+
+// RateLimitIP rejects client IPs that exceed limit with 429. It runs before
+// authentication, so requests without a valid token are counted too; the
+// limit is a ceiling for everyone behind the IP, and RateLimit then applies
+// the per-user limits.
+func RateLimitIP(store RateLimitStore, limit Limit) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if take(ctx, store, RouteGroupIP, "ip:"+ctx.ClientIP(), limit) {
+			ctx.Next()
+		}
+	}
+}
+
+// RateLimit rejects users that exceed the limit of the route group with
+// 429. It runs after authentication and limits authenticated users only:
+// other requests are limited per IP by RateLimitIP.
+func RateLimit(store RateLimitStore, limits RateLimits) gin.HandlerFunc {
+	uploads := routeSet(limits.UploadRoutes)
+
+	return func(ctx *gin.Context) {
+		caller, ok := callerOf(ctx)
+		if !ok || caller.UserID == "" {
+			ctx.Next()
+			return
+		}
+		group, limit := RouteGroupReads, limits.Reads
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			group, limit = RouteGroupWrites, limits.Writes
+			if uploads[ctx.FullPath()] && ctx.Request.Method != http.MethodDelete {
+				group, limit = RouteGroupUploads, limits.Uploads
+			}
+		}
+		if take(ctx, store, group, group+":user:"+caller.UserID, limit) {
+			ctx.Next()
+		}
+	}
+}
+
+// take counts the request against the bucket key of group and sets the
+// RateLimit-* headers describing its remaining quota. Over the limit, it
+// writes the 429 response and returns false. A limit without a rate or
+// burst is not enforced.
+func take(ctx *gin.Context, store RateLimitStore, group, key string, limit Limit) bool {
+	if limit.Burst <= 0 || limit.Rate <= 0 {
+		return true
+	}
+	result := store.Take(key, limit, time.Now())
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	ctx.Header("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+
+		strconv.Itoa(ceilSeconds(seconds(float64(limit.Burst)/limit.Rate)))+";policy=\""+group+"\"")
+
+	if !result.Allowed {
+		problem := response.NewProblem(http.StatusTooManyRequests, "rate limit exceeded for "+group)
+		problem.RetryAfter = int64(ceilSeconds(result.RetryAfter))
+		response.AbortWithProblem(ctx, problem)
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
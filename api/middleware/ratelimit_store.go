@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second and holding at
+// most Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitResult is the state of a bucket after a request was counted.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, set when not allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets. Implementations must be safe for
+// concurrent use; a shared store such as Redis lets several gateway
+// instances enforce one quota.
+type RateLimitStore interface {
+	Take(key string, limit Limit, now time.Time) RateLimitResult
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// MemoryStore is an in-process RateLimitStore. Buckets are spread over
+// shards with their own lock so concurrent requests for different keys
+// rarely contend.
+type MemoryStore struct {
+	shards []*memoryShard
+	// idle is how long an untouched bucket is kept; it must exceed the
+	// time any configured limit needs to refill.
+	idle time.Duration
+}
+
+// NewMemoryStore returns a store with the given number of shards, dropping
+// buckets unused for longer than idle.
+func NewMemoryStore(shards int, idle time.Duration) *MemoryStore {
+	if shards < 1 {
+		shards = 1
+	}
+	s := &MemoryStore{
+		shards: make([]*memoryShard, shards),
+		idle:   idle,
+	}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{buckets: map[string]*bucket{}}
+	}
+	return s
+}
+
+func (s *MemoryStore) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) RateLimitResult {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.sweep(now, s.idle)
+
+	burst := float64(limit.Burst)
+	b, ok := sh.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		sh.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+	}
+	b.last = now
+
+	result := RateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else if limit.Rate > 0 {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	if limit.Rate > 0 {
+		result.Reset = seconds((burst - b.tokens) / limit.Rate)
+	}
+
+	return result
+}
+
+// sweep drops idle buckets at most once per idle period. An idle bucket is
+// full again, so forgetting it does not change any client's quota.
+func (sh *memoryShard) sweep(now time.Time, idle time.Duration) {
+	if now.Sub(sh.lastSweep) < idle {
+		return
+	}
+	for key, b := range sh.buckets {
+		if now.Sub(b.last) > idle {
+			delete(sh.buckets, key)
+		}
+	}
+	sh.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
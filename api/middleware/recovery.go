@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"api_gateway/api/response"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panicking handler into a logged problem response instead
+// of an empty 500.
+func Recovery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				slog.ErrorContext(ctx, "panic recovered",
+					"panic", err,
+					"stack", string(debug.Stack()),
+				)
+				response.Abort(ctx, http.StatusInternalServerError, "")
+			}
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"api_gateway/pkg"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	RequestIDKey    = "request_id"
+	CallerKey       = "caller"
+
+	maxRequestIDLength = 128
+)
+
+// RequestID takes the request id from the incoming X-Request-Id header, or
+// generates one, echoes it back in the response and stores it with the client
+// IP in the request context so backend calls carry it as gRPC metadata.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		ctx.Set(RequestIDKey, id)
+		ctx.Header(RequestIDHeader, id)
+		setCaller(ctx, pkg.Caller{
+			RequestID: id,
+			ClientIP:  ctx.ClientIP(),
+		})
+		ctx.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// setCaller stores caller in the request context, where backend calls pick
+// it up, and in the gin context, which outlives the request context swaps
+// done by other middleware.
+func setCaller(ctx *gin.Context, caller pkg.Caller) {
+	ctx.Set(CallerKey, caller)
+	ctx.Request = ctx.Request.WithContext(pkg.WithCaller(ctx.Request.Context(), caller))
+	annotateSpan(ctx, caller)
+}
+
+func callerOf(ctx *gin.Context) (pkg.Caller, bool) {
+	caller, ok := ctx.Value(CallerKey).(pkg.Caller)
+	return caller, ok
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"api_gateway/pkg"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace from
+// an incoming traceparent header. Backend calls made with the request
+// context become its children.
+func Tracing(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service)
+}
+
+// annotateSpan tags the request span, if any, with the caller.
+func annotateSpan(ctx *gin.Context, caller pkg.Caller) {
+	span := trace.SpanFromContext(ctx.Request.Context())
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(attribute.String("request.id", caller.RequestID))
+	if caller.UserID != "" {
+		span.SetAttributes(attribute.String("enduser.id", caller.UserID))
+	}
+}
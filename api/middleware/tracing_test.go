@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"api_gateway/config"
+	pb "api_gateway/genproto/podcasts"
+	"api_gateway/pkg"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// tracedBackend records the traceparent of every call it serves.
+type tracedBackend struct {
+	pb.UnimplementedPodcastsServer
+
+	mu           sync.Mutex
+	traceparents []string
+}
+
+func (b *tracedBackend) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.traceparents = append(b.traceparents, strings.Join(md.Get("traceparent"), ","))
+}
+
+func (b *tracedBackend) GetPodcastById(ctx context.Context, id *pb.ID) (*pb.Podcast, error) {
+	b.record(ctx)
+	return &pb.Podcast{Id: id.GetId()}, nil
+}
+
+func (b *tracedBackend) ValidatePodcastId(ctx context.Context, id *pb.ID) (*pb.Success, error) {
+	b.record(ctx)
+	return &pb.Success{}, nil
+}
+
+func TestTracingSpanStructure(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &tracedBackend{}
+	server := grpc.NewServer()
+	pb.RegisterPodcastsServer(server, backend)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	client := pkg.NewPodcastsClient(&config.Config{PODCAST_SERVICE_PORT: ":" + port})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(Tracing("api_gateway"))
+	r.GET("/podcasts/:id", func(ctx *gin.Context) {
+		id := &pb.ID{Id: ctx.Param("id")}
+		if _, err := client.ValidatePodcastId(ctx, id); err != nil {
+			t.Errorf("ValidatePodcastId: %v", err)
+		}
+		if _, err := client.GetPodcastById(ctx, id); err != nil {
+			t.Errorf("GetPodcastById: %v", err)
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest(http.MethodGet, "/podcasts/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	var serverSpan tracetest.SpanStub
+	var clientSpans []tracetest.SpanStub
+	for _, span := range spans {
+		switch span.SpanKind {
+		case trace.SpanKindServer:
+			serverSpan = span
+		case trace.SpanKindClient:
+			clientSpans = append(clientSpans, span)
+		}
+	}
+
+	if got := serverSpan.SpanContext.TraceID().String(); got != traceID {
+		t.Fatalf("server span trace id = %q, want the incoming %q", got, traceID)
+	}
+	if got := serverSpan.Parent.SpanID().String(); got != spanID || !serverSpan.Parent.IsRemote() {
+		t.Errorf("server span parent = %q (remote %v), want the incoming %q", got, serverSpan.Parent.IsRemote(), spanID)
+	}
+
+	if len(clientSpans) != 2 {
+		t.Fatalf("got %d client spans, want 2: %v", len(clientSpans), spans)
+	}
+	for i, span := range clientSpans {
+		if span.Parent.SpanID() != serverSpan.SpanContext.SpanID() {
+			t.Errorf("client span %q has parent %s, want the server span %s",
+				span.Name, span.Parent.SpanID(), serverSpan.SpanContext.SpanID())
+		}
+		want := "00-" + traceID + "-" + span.SpanContext.SpanID().String() + "-01"
+		if backend.traceparents[i] != want {
+			t.Errorf("backend got traceparent %q for %q, want %q", backend.traceparents[i], span.Name, want)
+		}
+	}
+}
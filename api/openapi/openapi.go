@@ -0,0 +1,268 @@
+// Package openapi builds the OpenAPI 3.1 description of the gateway from the
+// routes registered on the router and the proto messages they exchange.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const Version = "3.1.0"
+
+// Operation documents a single route. Routes are keyed by "METHOD path" with
+// the path as registered on gin, e.g. "GET /listenup/podcasts/:id".
+type Operation struct {
+	// ID overrides the operation id derived from the handler name, which
+	// anonymous handlers do not have.
+	ID string
+	// Version of the API the route belongs to, appended to the operation id
+	// as the same handler may serve several versions.
+	Version string
+	Summary string
+	Tag     string
+	// Public operations do not require a bearer token.
+	Public     bool
+	Deprecated bool
+	Query      []Parameter
+	// Request is the message decoded from the JSON body, if any.
+	Request proto.Message
+	// Status is the success status, 200 when zero.
+	Status int
+	// Response is the message returned on success, if any. When ResponseKey
+	// is set the message is wrapped in an object under that key.
+	Response    proto.Message
+	ResponseKey string
+	// Paginated responses carry the items of the list message Response in
+	// an {items, limit, offset} envelope.
+	Paginated bool
+	// ContentType of a success body not described by a proto message.
+	ContentType string
+}
+
+// Parameter is a query string parameter.
+type Parameter struct {
+	Name        string
+	Description string
+	// Type is a JSON Schema type, "string" when empty.
+	Type  string
+	Array bool
+}
+
+// Document is the OpenAPI document served at /openapi.json.
+type Document struct {
+	OpenAPI    string                       `json:"openapi"`
+	Info       Info                         `json:"info"`
+	Paths      map[string]map[string]Schema `json:"paths"`
+	Components map[string]map[string]Schema `json:"components"`
+	Security   []map[string][]string        `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Build documents every route using operations, describing messages as
+// marshal renders them. It reports an error when a route has no operation,
+// or an operation matches no route, so the spec cannot drift from the
+// router; the document then covers the routes that are documented.
+func Build(info Info, routes gin.RoutesInfo, operations map[string]Operation,
+	marshal protojson.MarshalOptions) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]Schema{},
+		Security: []map[string][]string{
+			{"bearerAuth": {}},
+		},
+	}
+	components := schemas{defs: map[string]Schema{}, marshal: marshal}
+
+	missing := []string{}
+	documented := make(map[string]bool, len(operations))
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		op, ok := operations[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		documented[key] = true
+
+		path := templatePath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]Schema{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op.schema(route, components)
+	}
+
+	stale := []string{}
+	for key := range operations {
+		if !documented[key] {
+			stale = append(stale, key)
+		}
+	}
+	components.defs["Problem"] = problemSchema
+	doc.Components = map[string]map[string]Schema{
+		"schemas": components.defs,
+		"securitySchemes": {
+			"bearerAuth": Schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		},
+	}
+
+	if len(missing) > 0 || len(stale) > 0 {
+		sort.Strings(missing)
+		sort.Strings(stale)
+		return doc, fmt.Errorf("openapi: routes without an operation: [%s]; operations without a route: [%s]",
+			strings.Join(missing, ", "), strings.Join(stale, ", "))
+	}
+	return doc, nil
+}
+
+func (op Operation) schema(route gin.RouteInfo, components schemas) Schema {
+	operation := Schema{
+		"operationId": op.id(route.Handler),
+		"summary":     op.Summary,
+		"responses":   op.responses(components),
+	}
+	if op.Tag != "" {
+		operation["tags"] = []string{op.Tag}
+	}
+	if op.Deprecated {
+		operation["deprecated"] = true
+	}
+	if op.Public {
+		operation["security"] = []map[string][]string{}
+	}
+
+	parameters := []Schema{}
+	for _, name := range pathParams(route.Path) {
+		parameters = append(parameters, Schema{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   Schema{"type": "string", "format": "uuid"},
+		})
+	}
+	for _, param := range op.Query {
+		parameters = append(parameters, param.schema())
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		operation["requestBody"] = Schema{
+			"required": true,
+			"content":  content(components.ref(op.Request), op.Request),
+		}
+	}
+	return operation
+}
+
+func (op Operation) responses(components schemas) Schema {
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := Schema{"description": http.StatusText(status)}
+	switch {
+	case status == http.StatusNoContent:
+	case op.Response != nil:
+		body := components.ref(op.Response)
+		if op.Paginated {
+			body = components.page(op.Response)
+		}
+		if op.ResponseKey != "" {
+			body = Schema{"type": "object", "properties": Schema{op.ResponseKey: body}}
+		}
+		success["content"] = content(body, op.Response)
+	case op.ContentType != "":
+		success["content"] = Schema{op.ContentType: Schema{"schema": Schema{}}}
+	}
+
+	return Schema{
+		strconv.Itoa(status): success,
+		"default": Schema{
+			"description": "Error",
+			"content": Schema{
+				"application/problem+json": Schema{"schema": Schema{"$ref": "#/components/schemas/Problem"}},
+			},
+		},
+	}
+}
+
+// content describes a body in each format it can be exchanged in: JSON and
+// msgpack, which share the schema, and the protobuf encoding of msg.
+func content(schema Schema, msg proto.Message) Schema {
+	protobuf := Schema{
+		"type":        "string",
+		"format":      "binary",
+		"description": "Protobuf encoding of " + string(msg.ProtoReflect().Descriptor().FullName()) + ".",
+	}
+	return Schema{
+		"application/json":       Schema{"schema": schema},
+		"application/msgpack":    Schema{"schema": schema},
+		"application/x-protobuf": Schema{"schema": protobuf},
+	}
+}
+
+func (p Parameter) schema() Schema {
+	typ := p.Type
+	if typ == "" {
+		typ = "string"
+	}
+	schema := Schema{"type": typ}
+	if p.Array {
+		schema = Schema{"type": "array", "items": schema}
+	}
+	param := Schema{"name": p.Name, "in": "query", "schema": schema}
+	if p.Description != "" {
+		param["description"] = p.Description
+	}
+	return param
+}
+
+// templatePath turns gin's ":id" and "*path" segments into "{id}".
+func templatePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(path string) []string {
+	params := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// id derives the operation id from the handler name gin reports, e.g.
+// "api_gateway/api/handler.(*Handler).CreatePodcast-fm" becomes
+// "CreatePodcast".
+func (op Operation) id(handler string) string {
+	id := op.ID
+	if id == "" {
+		id = strings.TrimSuffix(handler[strings.LastIndex(handler, ".")+1:], "-fm")
+	}
+	if op.Version != "" {
+		id += "_" + op.Version
+	}
+	return id
+}
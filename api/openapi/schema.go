@@ -0,0 +1,164 @@
+package openapi
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.1.
+type Schema map[string]any
+
+// schemas collects the component schemas of every message reachable from the
+// documented requests and responses.
+type schemas struct {
+	defs map[string]Schema
+	// marshal is how responses are rendered, which decides field names and
+	// enum values.
+	marshal protojson.MarshalOptions
+}
+
+// ref returns a reference to the schema of msg, adding it and the messages
+// it refers to to the components when first seen.
+func (s schemas) ref(msg proto.Message) Schema {
+	return s.message(msg.ProtoReflect().Descriptor())
+}
+
+// page describes the paginated envelope of the list message msg: the
+// elements of its repeated message field under items, with the window.
+func (s schemas) page(msg proto.Message) Schema {
+	items := Schema{}
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); fd.IsList() && fd.Kind() == protoreflect.MessageKind {
+			items = s.message(fd.Message())
+			break
+		}
+	}
+	return Schema{
+		"type":     "object",
+		"required": []string{"items", "limit", "offset"},
+		"properties": Schema{
+			"items":  Schema{"type": "array", "items": items},
+			"limit":  Schema{"type": "integer"},
+			"offset": Schema{"type": "integer"},
+			// Set when there is a next page that can be resumed by cursor.
+			"next_cursor": Schema{"type": "string"},
+		},
+	}
+}
+
+func (s schemas) message(desc protoreflect.MessageDescriptor) Schema {
+	name := string(desc.FullName())
+	ref := Schema{"$ref": "#/components/schemas/" + name}
+	if _, ok := s.defs[name]; ok {
+		return ref
+	}
+
+	properties := Schema{}
+	object := Schema{"type": "object", "properties": properties}
+	// Reserve the name before walking fields so recursive messages terminate.
+	s.defs[name] = object
+
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		properties[s.name(fd)] = s.field(fd)
+	}
+	return ref
+}
+
+// name is the key of fd in JSON objects.
+func (s schemas) name(fd protoreflect.FieldDescriptor) string {
+	if s.marshal.UseProtoNames {
+		return string(fd.Name())
+	}
+	return fd.JSONName()
+}
+
+// field describes fd the way protojson renders it: bytes as base64 and
+// enums by name unless numbers are asked for. 64-bit integers are strings
+// on v2 and numbers on v1.
+func (s schemas) field(fd protoreflect.FieldDescriptor) Schema {
+	switch {
+	case fd.IsMap():
+		return Schema{"type": "object", "additionalProperties": s.singular(fd.MapValue())}
+	case fd.IsList():
+		return Schema{"type": "array", "items": s.singular(fd)}
+	}
+	return s.singular(fd)
+}
+
+func (s schemas) singular(fd protoreflect.FieldDescriptor) Schema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return Schema{"type": "boolean"}
+	case protoreflect.StringKind:
+		return Schema{"type": "string"}
+	case protoreflect.BytesKind:
+		return Schema{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return Schema{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return Schema{"type": "integer", "format": "int32", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return Schema{"type": []string{"integer", "string"}, "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return Schema{"type": []string{"integer", "string"}, "format": "uint64"}
+	case protoreflect.FloatKind:
+		return Schema{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return Schema{"type": "number", "format": "double"}
+	case protoreflect.EnumKind:
+		return s.enum(fd.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return s.message(fd.Message())
+	}
+	return Schema{}
+}
+
+func (s schemas) enum(desc protoreflect.EnumDescriptor) Schema {
+	values := desc.Values()
+	numbers := make([]int32, values.Len())
+	names := make([]string, values.Len())
+	for i := range numbers {
+		numbers[i] = int32(values.Get(i).Number())
+		names[i] = string(values.Get(i).Name())
+	}
+	if !s.marshal.UseEnumNumbers {
+		return Schema{"type": "string", "enum": names}
+	}
+	return Schema{
+		"type":        "integer",
+		"format":      "int32",
+		"enum":        numbers,
+		"description": string(desc.FullName()) + " values, in order: " + strings.Join(names, ", "),
+	}
+}
+
+// problemSchema mirrors response.Problem, the body of every error response.
+var problemSchema = Schema{
+	"type": "object",
+	"properties": Schema{
+		"type":        Schema{"type": "string", "format": "uri-reference"},
+		"title":       Schema{"type": "string"},
+		"status":      Schema{"type": "integer"},
+		"detail":      Schema{"type": "string"},
+		"instance":    Schema{"type": "string"},
+		"request_id":  Schema{"type": "string"},
+		"code":        Schema{"type": "string"},
+		"retry_after": Schema{"type": "integer"},
+		"errors": Schema{
+			"type": "array",
+			"items": Schema{
+				"type": "object",
+				"properties": Schema{
+					"field":       Schema{"type": "string"},
+					"description": Schema{"type": "string"},
+				},
+			},
+		},
+	},
+}
@@ -0,0 +1,61 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerHTML string
+
+var swaggerTemplate = template.Must(template.New("swagger").Parse(swaggerHTML))
+
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css
+var swaggerUI embed.FS
+
+var swaggerAssets = func() http.FileSystem {
+	sub, err := fs.Sub(swaggerUI, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(sub)
+}()
+
+// Handler serves the document as JSON. It is encoded once, the routes do not
+// change after startup.
+func (d *Document) Handler() gin.HandlerFunc {
+	body, err := json.Marshal(d)
+	if err != nil {
+		panic("openapi: cannot encode document: " + err.Error())
+	}
+	return func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json", body)
+	}
+}
+
+// UI serves a Swagger UI page that loads the document from specURL and the
+// Swagger UI assets from assetsURL, where UIAsset is routed.
+func (d *Document) UI(specURL, assetsURL string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		err := swaggerTemplate.Execute(ctx.Writer, struct {
+			Title     string
+			SpecURL   string
+			AssetsURL string
+		}{d.Info.Title, specURL, assetsURL})
+		if err != nil {
+			ctx.Error(err)
+		}
+	}
+}
+
+// UIAsset serves the embedded Swagger UI file named by the asset parameter.
+func UIAsset(ctx *gin.Context) {
+	ctx.FileFromFS(ctx.Param("asset"), swaggerAssets)
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"api_gateway/api/openapi"
+	"api_gateway/config"
+	pbCollaboration "api_gateway/genproto/collaborations"
+	pbComments "api_gateway/genproto/comments"
+	pbEpisodeMetadata "api_gateway/genproto/episode_metadata"
+	pbEpisodes "api_gateway/genproto/episodes"
+	pbPodcasts "api_gateway/genproto/podcasts"
+	pbUserManagement "api_gateway/genproto/user"
+	pbUserInteractions "api_gateway/genproto/user_interactions"
+	"net/http"
+	"strings"
+)
+
+var pagination = []openapi.Parameter{
+	{Name: "limit", Type: "integer", Description: "Maximum number of items to return, capped by the server. Defaults to a server-chosen page size."},
+	{Name: "offset", Type: "integer", Description: "Number of items to skip. Defaults to 0."},
+}
+
+// feedPagination pages feeds, which can also be resumed from a cursor so
+// that items added meanwhile are neither skipped nor repeated.
+var feedPagination = append(pagination[:len(pagination):len(pagination)], openapi.Parameter{
+	Name: "cursor", Description: "Opaque cursor of the next page, from next_cursor or a Link header. Replaces offset. " +
+		"The item it points past is looked for among the server's maximum page size of items around where it was, " +
+		"which costs one extra backend read; if more than half that many items were added or removed before it since, " +
+		"the request fails with 410 and the list has to be started again from the first page.",
+})
+
+// rootOperations documents the routes outside the API groups.
+var rootOperations = map[string]openapi.Operation{
+	"GET /healthz": {
+		Summary: "Liveness probe", Tag: "health", Public: true,
+		ContentType: "application/json",
+	},
+	"GET /readyz": {
+		Summary: "Readiness probe, reporting each backend", Tag: "health", Public: true,
+		ContentType: "application/json",
+	},
+	"GET /metrics": {
+		ID: "Metrics", Summary: "Prometheus metrics", Tag: "health", Public: true,
+		ContentType: "text/plain",
+	},
+}
+
+// v1Operations documents the v1 routes, keyed by their path relative to the
+// version group.
+var v1Operations = map[string]openapi.Operation{
+	"GET /users/:id": {
+		Summary: "Get a user", Tag: "users",
+		Response: &pbUserManagement.User{}, ResponseKey: "User",
+	},
+	"PUT /users/:id": {
+		Summary: "Update a user", Tag: "users",
+		Request: &pbUserManagement.User{}, Status: http.StatusNoContent,
+	},
+	"DELETE /users/:id": {
+		Summary: "Delete a user", Tag: "users",
+		Status: http.StatusNoContent,
+	},
+	"GET /users/:id/profile": {
+		Summary: "Get a user's profile", Tag: "users",
+		Response: &pbUserManagement.Profile{}, ResponseKey: "User Profile",
+	},
+	"PUT /users/:id/profile": {
+		Summary: "Update a user's profile", Tag: "users",
+		Request: &pbUserManagement.Profile{}, Status: http.StatusNoContent,
+	},
+	"GET /users/:id/podcasts": {
+		Summary: "List a user's podcasts", Tag: "podcasts", Query: pagination,
+		Status: http.StatusAccepted, Response: &pbPodcasts.UserPodcasts{},
+	},
+
+	"POST /podcasts/": {
+		Summary: "Create a podcast", Tag: "podcasts",
+		Request: &pbPodcasts.PodcastCreate{}, Status: http.StatusAccepted, Response: &pbPodcasts.ID{},
+	},
+	"GET /podcasts/:id": {
+		Summary: "Get a podcast", Tag: "podcasts",
+		Status: http.StatusAccepted, Response: &pbPodcasts.Podcast{},
+	},
+	"PUT /podcasts/:id": {
+		Summary: "Update a podcast", Tag: "podcasts",
+		Request: &pbPodcasts.PodcastUpdate{}, Status: http.StatusAccepted, Response: &pbPodcasts.Void{},
+	},
+	"DELETE /podcasts/:id": {
+		Summary: "Delete a podcast", Tag: "podcasts",
+		Status: http.StatusAccepted, Response: &pbPodcasts.Void{},
+	},
+	"POST /podcasts/:id/publish": {
+		Summary: "Publish a podcast", Tag: "podcasts",
+		Status: http.StatusAccepted, Response: &pbPodcasts.Success{},
+	},
+
+	"POST /podcasts/:id/episodes": {
+		Summary: "Upload an episode", Tag: "episodes",
+		Request: &pbEpisodes.EpisodeCreate{}, Status: http.StatusAccepted, Response: &pbEpisodes.ID{},
+	},
+	"GET /podcasts/:id/episodes": {
+		Summary: "List a podcast's episodes", Tag: "episodes", Query: feedPagination,
+		Status: http.StatusAccepted, Response: &pbEpisodes.Episodes{},
+	},
+	"GET /podcasts/:id/episodes/:episodeid": {
+		Summary: "Get an episode, with the ETag to send as If-Match when updating it", Tag: "episodes",
+		Response: &pbEpisodes.Episode{},
+	},
+	"PUT /podcasts/:id/episodes/:episodeid": {
+		Summary: "Update an episode", Tag: "episodes",
+		Request: &pbEpisodes.EpisodeCreate{}, Status: http.StatusAccepted, Response: &pbEpisodes.Void{},
+	},
+	"DELETE /podcasts/:id/episodes/:episodeid": {
+		Summary: "Delete an episode", Tag: "episodes",
+		Status: http.StatusAccepted, Response: &pbEpisodes.Void{},
+	},
+
+	"POST /collaborations/invite": {
+		Summary: "Invite a collaborator", Tag: "collaborations",
+		Request: &pbCollaboration.CreateInvite{}, Status: http.StatusCreated, Response: &pbCollaboration.ID{},
+	},
+	"PUT /collaborations/invite/:id/respond": {
+		Summary: "Respond to an invitation", Tag: "collaborations",
+		Request: &pbCollaboration.CreateCollaboration{}, Status: http.StatusCreated,
+		Response: &pbCollaboration.ID{}, ResponseKey: "Id",
+	},
+	"GET /podcasts/:id/collaborators": {
+		Summary: "List a podcast's collaborators", Tag: "collaborations",
+		Response: &pbCollaboration.Collaborators{},
+	},
+	"PUT /podcasts/:id/collaborators/:userid": {
+		Summary: "Update a collaborator", Tag: "collaborations",
+		Request: &pbCollaboration.UpdateCollaborator{},
+	},
+	"DELETE /podcasts/:id/collaborators/:userid": {
+		Summary: "Remove a collaborator", Tag: "collaborations",
+	},
+
+	"POST /podcasts/:id/comments": {
+		Summary: "Comment on a podcast", Tag: "comments",
+		Request: &pbComments.CreateComment{},
+	},
+	"GET /podcasts/:id/comments": {
+		Summary: "List a podcast's comments", Tag: "comments", Query: feedPagination,
+		Response: &pbComments.AllComments{},
+	},
+
+	"GET /discover/trending": {
+		Summary: "Trending podcasts", Tag: "discover", Query: pagination,
+		Response: &pbEpisodeMetadata.Podcasts{}, ResponseKey: "Trending Podcasts",
+	},
+	"GET /discover/recommended/:userid": {
+		Summary: "Podcasts recommended to a user", Tag: "discover", Query: pagination,
+		Response: &pbEpisodeMetadata.Podcasts{}, ResponseKey: "Recommended Podcasts",
+	},
+	"GET /discover/genres": {
+		Summary: "Podcasts of the given genres", Tag: "discover",
+		Query: append([]openapi.Parameter{
+			{Name: "genres", Array: true, Description: "Genres to match, repeated."},
+		}, pagination...),
+		Response: &pbEpisodeMetadata.Podcasts{}, ResponseKey: "Podcasts",
+	},
+	"GET /search": {
+		Summary: "Search episodes by title", Tag: "discover",
+		Request:  &pbEpisodeMetadata.Title{},
+		Response: &pbEpisodeMetadata.Episode{}, ResponseKey: "Episode",
+	},
+
+	"POST /podcasts/:id/like": {
+		Summary: "Like an episode", Tag: "interactions",
+		Request:  &pbUserInteractions.InteractEpisode{},
+		Response: &pbUserInteractions.ID{}, ResponseKey: "New Interaction ID",
+	},
+	"DELETE /podcasts/:id/like": {
+		Summary: "Remove a like", Tag: "interactions",
+		Request: &pbUserInteractions.DeleteLike{}, ContentType: "application/json",
+	},
+	"POST /podcasts/:id/listen": {
+		Summary: "Record a listen", Tag: "interactions",
+		Request:  &pbUserInteractions.InteractEpisode{},
+		Response: &pbUserInteractions.ID{}, ResponseKey: "New Interaction ID",
+	},
+}
+
+// v2Operations documents the routes whose contract changed in v2; the
+// others are as in v1.
+var v2Operations = map[string]openapi.Operation{
+	"GET /users/:id/podcasts": {
+		Summary: "List a user's podcasts", Tag: "podcasts", Query: pagination,
+		Response: &pbPodcasts.UserPodcasts{}, Paginated: true,
+	},
+	"GET /podcasts/:id/episodes": {
+		Summary: "List a podcast's episodes", Tag: "episodes", Query: feedPagination,
+		Response: &pbEpisodes.Episodes{}, Paginated: true,
+	},
+	"GET /podcasts/:id/comments": {
+		Summary: "List a podcast's comments", Tag: "comments", Query: feedPagination,
+		Response: &pbComments.AllComments{}, Paginated: true,
+	},
+	"GET /discover/trending": {
+		Summary: "Trending podcasts", Tag: "discover", Query: pagination,
+		Response: &pbEpisodeMetadata.Podcasts{}, Paginated: true,
+	},
+	"GET /discover/recommended/:userid": {
+		Summary: "Podcasts recommended to a user", Tag: "discover", Query: pagination,
+		Response: &pbEpisodeMetadata.Podcasts{}, Paginated: true,
+	},
+	"GET /discover/genres": {
+		Summary: "Podcasts of the given genres", Tag: "discover",
+		Query: append([]openapi.Parameter{
+			{Name: "genres", Array: true, Description: "Genres to match, repeated."},
+		}, pagination...),
+		Response: &pbEpisodeMetadata.Podcasts{}, Paginated: true,
+	},
+	"GET /search": {
+		Summary: "Search episodes by title", Tag: "discover",
+		Query:    []openapi.Parameter{{Name: "title", Description: "Episode title to search for."}},
+		Response: &pbEpisodeMetadata.Episode{},
+	},
+}
+
+// documentedOperations documents every route registered by apiRouter;
+// TestSpecCoversRoutes fails when a route is missing here.
+func documentedOperations(cfg *config.Config) map[string]openapi.Operation {
+	ops := map[string]openapi.Operation{}
+	for key, op := range rootOperations {
+		ops[key] = op
+	}
+	addVersion(ops, "legacy", apiPrefix, v1Operations, !cfg.API_LEGACY_DEPRECATED_AT.IsZero())
+	addVersion(ops, "v1", apiPrefix+v1Group, v1Operations, !cfg.API_V1_DEPRECATED_AT.IsZero())
+	addVersion(ops, "v2", apiPrefix+v2Group, v1Operations, false)
+	addVersion(ops, "v2", apiPrefix+v2Group, v2Operations, false)
+	return ops
+}
+
+func addVersion(ops map[string]openapi.Operation, version, prefix string,
+	operations map[string]openapi.Operation, deprecated bool) {
+	for key, op := range operations {
+		method, path, _ := strings.Cut(key, " ")
+		op.Version = version
+		op.Deprecated = deprecated
+		ops[method+" "+prefix+path] = op
+	}
+}
@@ -0,0 +1,151 @@
+package response
+
+import (
+	"api_gateway/pkg"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// timeLayouts are the formats backends use for updated_at.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999",
+	"2006-01-02 15:04:05.999999-07:00",
+	"2006-01-02 15:04:05.999999-07",
+	"2006-01-02 15:04:05.999999",
+}
+
+// ETag returns the strong entity tag of msg as it is served in format,
+// computed over its deterministic protobuf encoding. The format is part of
+// the hash, so each representation of msg has its own tag.
+func ETag(msg proto.Message, format string) string {
+	if format == "" {
+		format = MIMEJSON
+	}
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(pkg.Redact(msg))
+	h := sha256.New()
+	h.Write([]byte(format + "\x00"))
+	h.Write(b)
+	return `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// ConditionalJSON writes msg like JSON along with its ETag and, when msg or
+// the messages it lists have an updated_at, Last-Modified. A GET or HEAD
+// from a client that already has this version is answered with 304.
+func ConditionalJSON(ctx *gin.Context, code int, msg proto.Message) {
+	if notModified(ctx, msg) {
+		return
+	}
+	JSON(ctx, code, msg)
+}
+
+// notModified sets the validators of msg and, when the client of a GET or
+// HEAD already has this version, answers 304 and reports true.
+func notModified(ctx *gin.Context, msg proto.Message) bool {
+	etag := ETag(msg, ctx.GetString(FormatKey))
+	ctx.Header("ETag", etag)
+	modified, hasModified := LastModified(msg)
+	if hasModified {
+		ctx.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+		return false
+	}
+	fresh := false
+	if header := ctx.GetHeader("If-None-Match"); header != "" {
+		fresh = IfNoneMatch(header, etag)
+	} else if since, err := http.ParseTime(ctx.GetHeader("If-Modified-Since")); err == nil && hasModified {
+		fresh = !modified.Truncate(time.Second).After(since)
+	}
+	if fresh {
+		ctx.Status(http.StatusNotModified)
+	}
+	return fresh
+}
+
+// IfNoneMatch reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for it.
+func IfNoneMatch(header, etag string) bool {
+	return matchETag(header, etag, true)
+}
+
+// IfMatch reports whether an If-Match header matches etag, using strong
+// comparison. "*" matches any current version.
+func IfMatch(header, etag string) bool {
+	return matchETag(header, etag, false)
+}
+
+// IfMatchAny reports whether an If-Match header matches msg as served in
+// any of Formats, as a client may update a resource in another format than
+// it read it in.
+func IfMatchAny(header string, msg proto.Message) bool {
+	for _, format := range Formats {
+		if IfMatch(header, ETag(msg, format)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// LastModified returns the updated_at of msg or, for a message listing
+// others, the latest updated_at among them.
+func LastModified(msg proto.Message) (time.Time, bool) {
+	m := msg.ProtoReflect()
+	if t, ok := updatedAt(m); ok {
+		return t, true
+	}
+
+	latest, found := time.Time{}, false
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !fd.IsList() || fd.Kind() != protoreflect.MessageKind {
+			continue
+		}
+		list := m.Get(fd).List()
+		for j := 0; j < list.Len(); j++ {
+			if t, ok := updatedAt(list.Get(j).Message()); ok && t.After(latest) {
+				latest, found = t, true
+			}
+		}
+	}
+	return latest, found
+}
+
+func updatedAt(m protoreflect.Message) (time.Time, bool) {
+	fd := m.Descriptor().Fields().ByName("updated_at")
+	if fd == nil || fd.Kind() != protoreflect.StringKind {
+		return time.Time{}, false
+	}
+	value := m.Get(fd).String()
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,81 @@
+package response
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEJSON     = "application/json"
+	MIMEProtobuf = "application/x-protobuf"
+	MIMEMsgPack  = "application/msgpack"
+)
+
+// FormatKey holds the media type negotiated for the response body.
+const FormatKey = "response_format"
+
+// Formats are the media types bodies can be encoded as, JSON first as it is
+// served when the client has no preference.
+var Formats = []string{MIMEJSON, MIMEProtobuf, MIMEMsgPack}
+
+// formatAliases maps other names in use for the formats to the one served.
+var formatAliases = map[string]string{
+	MIMEJSON:                          MIMEJSON,
+	MIMEProtobuf:                      MIMEProtobuf,
+	"application/protobuf":            MIMEProtobuf,
+	"application/vnd.google.protobuf": MIMEProtobuf,
+	MIMEMsgPack:                       MIMEMsgPack,
+	"application/x-msgpack":           MIMEMsgPack,
+}
+
+// Format returns the format a media type names, if it is one of Formats.
+func Format(mediaType string) (string, bool) {
+	format, ok := formatAliases[strings.ToLower(mediaType)]
+	return format, ok
+}
+
+// Negotiate picks the format of the response from an Accept header among
+// offered, a subset of Formats in the same order: the acceptable one with
+// the highest quality, earlier formats breaking ties. Wildcards match JSON.
+// It returns false when none is acceptable.
+func Negotiate(accept string, offered []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return MIMEJSON, true
+	}
+
+	best, bestQ := "", 0.0
+	for _, candidate := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(candidate))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+
+		format, ok := Format(mediaType)
+		if mediaType == "*/*" || mediaType == "application/*" {
+			format, ok = MIMEJSON, true
+		}
+		if !ok || q <= 0 || formatRank(offered, format) == len(offered) {
+			continue
+		}
+		if q > bestQ || (q == bestQ && formatRank(offered, format) < formatRank(offered, best)) {
+			best, bestQ = format, q
+		}
+	}
+	return best, best != ""
+}
+
+func formatRank(formats []string, format string) int {
+	for i, f := range formats {
+		if f == format {
+			return i
+		}
+	}
+	return len(formats)
+}
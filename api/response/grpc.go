@@ -0,0 +1,100 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusClientClosedRequest is answered when the caller went away before the
+// backend replied.
+const StatusClientClosedRequest = 499
+
+// HTTPStatus maps a gRPC status code to the HTTP status the gateway answers
+// with.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return StatusClientClosedRequest
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// safeMessage reports whether the backend message for code describes the
+// caller's request and may be shown to the client. Messages of server faults
+// can leak internals and are replaced by the HTTP status text.
+func safeMessage(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange, codes.NotFound,
+		codes.AlreadyExists, codes.Aborted, codes.PermissionDenied,
+		codes.Unauthenticated, codes.ResourceExhausted, codes.FailedPrecondition:
+		return true
+	}
+	return false
+}
+
+// FromGRPC translates an error returned by a backend call into the problem
+// the gateway responds with. Errors that carry no gRPC status are treated as
+// internal.
+func FromGRPC(err error) Problem {
+	st, _ := status.FromError(err)
+
+	problem := NewProblem(HTTPStatus(st.Code()), "")
+	problem.Type = TypeBackend
+	problem.Code = st.Code().String()
+	problem.Detail = problem.Title
+	if safeMessage(st.Code()) && st.Message() != "" {
+		problem.Detail = st.Message()
+	}
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				problem.Errors = append(problem.Errors, FieldViolation{
+					Field:       v.GetField(),
+					Description: v.GetDescription(),
+				})
+			}
+		case *errdetails.RetryInfo:
+			if delay := d.GetRetryDelay(); delay != nil {
+				problem.RetryAfter = int64(delay.AsDuration().Seconds() + 0.5)
+			}
+		}
+	}
+	if len(problem.Errors) > 0 {
+		problem.Type = TypeValidation
+	}
+
+	return problem
+}
+
+// AbortWithGRPCError writes the translation of a backend error and stops the
+// handler chain.
+func AbortWithGRPCError(ctx *gin.Context, err error) {
+	AbortWithProblem(ctx, FromGRPC(err))
+}
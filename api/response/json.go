@@ -0,0 +1,254 @@
+package response
+
+import (
+	"api_gateway/pkg"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NumericInt64Key marks a request whose responses render 64-bit integers
+// as JSON numbers, as the v1 contract has them, rather than as the strings
+// protojson writes.
+const NumericInt64Key = "numeric_int64"
+
+var marshalOptions atomic.Pointer[protojson.MarshalOptions]
+
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+func init() {
+	SetMarshalOptions(protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: true})
+}
+
+// SetMarshalOptions replaces the options proto messages are rendered with.
+func SetMarshalOptions(opts protojson.MarshalOptions) {
+	marshalOptions.Store(&opts)
+}
+
+// MarshalOptions returns the options proto messages are rendered with.
+func MarshalOptions() protojson.MarshalOptions {
+	return *marshalOptions.Load()
+}
+
+// JSON writes obj as the response body, in the format negotiated for the
+// request and JSON when none was. Proto messages, including those wrapped
+// in a gin.H or listed in a slice, have their sensitive fields stripped and
+// are rendered with protojson.
+func JSON(ctx *gin.Context, code int, obj any) {
+	write(ctx, code, obj, nil)
+}
+
+// write writes obj in the negotiated format. In protobuf it writes native
+// instead when set, or else obj itself or the single message obj wraps; a
+// body with no such message cannot be sent as protobuf and gets 406. The
+// Negotiate middleware already refuses protobuf on routes documented
+// without a proto response, before their handler has any effect.
+func write(ctx *gin.Context, code int, obj any, native proto.Message) {
+	if !bodyAllowed(code) {
+		ctx.Status(code)
+		return
+	}
+
+	format := ctx.GetString(FormatKey)
+	if format == MIMEProtobuf {
+		if native == nil {
+			native = wrapped(obj)
+		}
+		if native == nil {
+			Abort(ctx, http.StatusNotAcceptable, "this response has no protobuf representation; accept "+MIMEJSON)
+			return
+		}
+		b, err := proto.Marshal(pkg.Redact(native))
+		if err != nil {
+			Abort(ctx, http.StatusInternalServerError, "cannot render response")
+			slog.ErrorContext(ctx, "cannot render response", "error", err)
+			return
+		}
+		ctx.Data(code, MIMEProtobuf, b)
+		return
+	}
+
+	rendered, err := render(obj, MarshalOptions(), ctx.GetBool(NumericInt64Key))
+	if err == nil && format == MIMEMsgPack {
+		var b []byte
+		if b, err = msgpack(rendered); err == nil {
+			ctx.Data(code, MIMEMsgPack, b)
+			return
+		}
+	}
+	if err != nil {
+		Abort(ctx, http.StatusInternalServerError, "cannot render response")
+		slog.ErrorContext(ctx, "cannot render response", "error", err)
+		return
+	}
+	ctx.JSON(code, rendered)
+}
+
+// wrapped returns obj when it is a proto message, or the message a gin.H
+// holds under its only key.
+func wrapped(obj any) proto.Message {
+	switch v := obj.(type) {
+	case proto.Message:
+		return v
+	case gin.H:
+		if len(v) == 1 {
+			for _, value := range v {
+				msg, _ := value.(proto.Message)
+				return msg
+			}
+		}
+	}
+	return nil
+}
+
+// msgpack encodes a rendered body: its JSON is decoded into plain values so
+// the field names and representations are the same as in JSON.
+func msgpack(rendered any) ([]byte, error) {
+	b, err := json.Marshal(rendered)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	err = codec.NewEncoderBytes(&out, msgpackHandle).Encode(plain(value))
+	return out, err
+}
+
+// plain replaces the json.Numbers in v by integers, or floats when they
+// have a fraction.
+func plain(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, value := range v {
+			v[key] = plain(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = plain(value)
+		}
+	}
+	return v
+}
+
+func bodyAllowed(code int) bool {
+	return code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+func render(obj any, opts protojson.MarshalOptions, numericInt64 bool) (any, error) {
+	switch v := obj.(type) {
+	case proto.Message:
+		b, err := opts.Marshal(pkg.Redact(v))
+		if err == nil && opts.EmitUnpopulated {
+			b, err = pkg.RedactJSON(b)
+		}
+		if err == nil && numericInt64 {
+			b, err = unquoteInt64(b, v.ProtoReflect().Descriptor())
+		}
+		return json.RawMessage(b), err
+	case []proto.Message:
+		rendered := make([]any, len(v))
+		for i, msg := range v {
+			var err error
+			if rendered[i], err = render(msg, opts, numericInt64); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case gin.H:
+		rendered := make(gin.H, len(v))
+		for key, value := range v {
+			var err error
+			if rendered[key], err = render(value, opts, numericInt64); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	}
+	return obj, nil
+}
+
+// unquoteInt64 rewrites the 64-bit integers protojson quotes in b, a
+// rendering of a desc message, as JSON numbers.
+func unquoteInt64(b []byte, desc protoreflect.MessageDescriptor) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(unquoteMessage(value, desc)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+func unquoteMessage(v any, desc protoreflect.MessageDescriptor) any {
+	object, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	fields := desc.Fields()
+	for key, value := range object {
+		fd := fields.ByJSONName(key)
+		if fd == nil {
+			fd = fields.ByName(protoreflect.Name(key))
+		}
+		if fd == nil {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			if entries, ok := value.(map[string]any); ok {
+				for k, entry := range entries {
+					entries[k] = unquoteValue(entry, fd.MapValue())
+				}
+			}
+		case fd.IsList():
+			if items, ok := value.([]any); ok {
+				for i, item := range items {
+					items[i] = unquoteValue(item, fd)
+				}
+			}
+		default:
+			object[key] = unquoteValue(value, fd)
+		}
+	}
+	return object
+}
+
+func unquoteValue(v any, fd protoreflect.FieldDescriptor) any {
+	switch fd.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if s, ok := v.(string); ok {
+			return json.Number(s)
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return unquoteMessage(v, fd.Message())
+	}
+	return v
+}
@@ -0,0 +1,109 @@
+package response
+
+import (
+	pbAuthentication "api_gateway/genproto/authentication"
+	pbUser "api_gateway/genproto/user"
+	"api_gateway/pkg"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// secretName matches the names of fields that must never leave the gateway,
+// whether or not the redactor is configured for them.
+var secretName = regexp.MustCompile(`(?i)password|passwd|token|secret|api_?key`)
+
+func secretMessages() map[string]proto.Message {
+	return map[string]proto.Message{
+		"user": &pbUser.User{
+			Id:       "5f0c5e4e-8f4a-4d6c-9d4b-2f9e0c3e6a11",
+			Username: "alice",
+			Email:    "alice@example.com",
+			Password: "hunter2",
+		},
+		"login": &pbAuthentication.LoginResponse{
+			Id:       "5f0c5e4e-8f4a-4d6c-9d4b-2f9e0c3e6a11",
+			Username: "alice",
+			Password: "hunter2",
+		},
+	}
+}
+
+// secretKeys returns the paths of the members of the JSON document b named
+// like a secret.
+func secretKeys(t *testing.T, b []byte) []string {
+	t.Helper()
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+
+	var found []string
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, value := range v {
+				if secretName.MatchString(key) {
+					found = append(found, path+"."+key)
+				}
+				walk(path+"."+key, value)
+			}
+		case []any:
+			for _, value := range v {
+				walk(path+"[]", value)
+			}
+		}
+	}
+	walk("", doc)
+	return found
+}
+
+func TestJSONOmitsSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer SetMarshalOptions(MarshalOptions())
+
+	for _, opts := range []protojson.MarshalOptions{
+		{EmitUnpopulated: true, UseProtoNames: true},
+		{EmitUnpopulated: false, UseProtoNames: false},
+	} {
+		SetMarshalOptions(opts)
+		for name, msg := range secretMessages() {
+			for _, body := range []any{msg, gin.H{"data": msg}, []proto.Message{msg}} {
+				w := httptest.NewRecorder()
+				ctx, _ := gin.CreateTestContext(w)
+				ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+				JSON(ctx, http.StatusOK, body)
+
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s: status %d: %s", name, w.Code, w.Body)
+				}
+				if keys := secretKeys(t, w.Body.Bytes()); len(keys) > 0 {
+					t.Errorf("%s rendered with %+v contains %v: %s", name, opts, keys, w.Body)
+				}
+			}
+		}
+	}
+}
+
+func TestLogOmitsSecrets(t *testing.T) {
+	for name, msg := range secretMessages() {
+		var buf bytes.Buffer
+		logger := pkg.NewLogger(&buf, "debug")
+
+		logger.Info("payload", "request", msg, slog.Group("call", "reply", msg))
+
+		if keys := secretKeys(t, buf.Bytes()); len(keys) > 0 {
+			t.Errorf("%s logged with %v: %s", name, keys, buf.Bytes())
+		}
+	}
+}
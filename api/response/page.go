@@ -0,0 +1,103 @@
+package response
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Pagination is the window of a list served by a paginated route.
+type Pagination struct {
+	Limit  int
+	Offset int
+	// NextCursor, when set, resumes the list right after this page.
+	NextCursor string
+}
+
+// Page writes the list message msg in the paginated envelope
+// {"items": [...], "limit": n, "offset": n, "next_cursor": "..."}, with Link
+// headers to the neighbouring pages and the validators ConditionalJSON sets.
+// In protobuf, msg is sent as is and the Link headers carry the window.
+func Page(ctx *gin.Context, code int, msg proto.Message, page Pagination) {
+	items := Items(msg)
+	Links(ctx, page, len(items))
+	if notModified(ctx, msg) {
+		return
+	}
+	body := gin.H{
+		"items":  items,
+		"limit":  page.Limit,
+		"offset": page.Offset,
+	}
+	if page.NextCursor != "" {
+		body["next_cursor"] = page.NextCursor
+	}
+	write(ctx, code, body, msg)
+}
+
+// Items returns the elements of the repeated message field of a list
+// message such as Episodes or AllComments.
+func Items(msg proto.Message) []proto.Message {
+	m := msg.ProtoReflect()
+	fd := ItemsField(m.Descriptor())
+	if fd == nil {
+		return []proto.Message{}
+	}
+	list := m.Get(fd).List()
+	items := make([]proto.Message, list.Len())
+	for i := range items {
+		items[i] = list.Get(i).Message().Interface()
+	}
+	return items
+}
+
+// ItemsField returns the repeated message field of a list message, or nil
+// when it has none.
+func ItemsField(desc protoreflect.MessageDescriptor) protoreflect.FieldDescriptor {
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); fd.IsList() && fd.Kind() == protoreflect.MessageKind {
+			return fd
+		}
+	}
+	return nil
+}
+
+// Links adds RFC 8288 Link headers to the previous page and, when this one
+// holds count items and is full, to the next one, by cursor when the page
+// has one.
+func Links(ctx *gin.Context, page Pagination, count int) {
+	if page.Limit > 0 && count >= page.Limit {
+		query := url.Values{"limit": {strconv.Itoa(page.Limit)}}
+		if page.NextCursor != "" {
+			query.Set("cursor", page.NextCursor)
+		} else {
+			query.Set("offset", strconv.Itoa(page.Offset+page.Limit))
+		}
+		ctx.Writer.Header().Add("Link", pageLink(ctx, query, "next"))
+	}
+	if page.Offset > 0 {
+		query := url.Values{
+			"limit":  {strconv.Itoa(page.Limit)},
+			"offset": {strconv.Itoa(max(page.Offset-page.Limit, 0))},
+		}
+		ctx.Writer.Header().Add("Link", pageLink(ctx, query, "prev"))
+	}
+}
+
+// pageLink links to the current URL with its pagination parameters
+// replaced by window.
+func pageLink(ctx *gin.Context, window url.Values, rel string) string {
+	query := ctx.Request.URL.Query()
+	for _, name := range []string{"limit", "offset", "cursor"} {
+		query.Del(name)
+	}
+	for name, values := range window {
+		query[name] = values
+	}
+	target := url.URL{Path: ctx.Request.URL.Path, RawQuery: query.Encode()}
+	return "<" + target.String() + `>; rel="` + rel + `"`
+}
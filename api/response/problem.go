@@ -0,0 +1,92 @@
+package response
+
+import (
+	"api_gateway/pkg"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	TypeDefault    = "about:blank"
+	TypeValidation = "/problems/validation-error"
+	TypeBackend    = "/problems/backend-error"
+)
+
+// FieldViolation points at a single invalid request field.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Problem is the RFC 7807 error body returned by every handler and
+// middleware of the gateway.
+type Problem struct {
+	Type       string           `json:"type"`
+	Title      string           `json:"title"`
+	Status     int              `json:"status"`
+	Detail     string           `json:"detail,omitempty"`
+	Instance   string           `json:"instance,omitempty"`
+	RequestID  string           `json:"request_id,omitempty"`
+	Code       string           `json:"code,omitempty"`
+	Errors     []FieldViolation `json:"errors,omitempty"`
+	RetryAfter int64            `json:"retry_after,omitempty"`
+}
+
+// NewProblem returns a problem of the default type for the given status.
+func NewProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   TypeDefault,
+		Title:  statusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Abort writes a problem for status with the given detail and stops the
+// handler chain.
+func Abort(ctx *gin.Context, status int, detail string) {
+	AbortWithProblem(ctx, NewProblem(status, detail))
+}
+
+// AbortWithViolations writes a validation problem listing the offending
+// fields.
+func AbortWithViolations(ctx *gin.Context, detail string, violations []FieldViolation) {
+	problem := NewProblem(http.StatusBadRequest, detail)
+	problem.Type = TypeValidation
+	problem.Errors = violations
+	AbortWithProblem(ctx, problem)
+}
+
+// AbortWithProblem fills in the request specific members of problem, writes
+// it as application/problem+json and stops the handler chain.
+func AbortWithProblem(ctx *gin.Context, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = TypeDefault
+	}
+	if problem.Title == "" {
+		problem.Title = statusText(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = ctx.Request.URL.Path
+	}
+	if caller, ok := pkg.CallerFromContext(ctx.Request.Context()); ok {
+		problem.RequestID = caller.RequestID
+	}
+	if problem.RetryAfter > 0 {
+		ctx.Header("Retry-After", strconv.FormatInt(problem.RetryAfter, 10))
+	}
+
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
+
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
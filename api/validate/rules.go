@@ -0,0 +1,154 @@
+package validate
+
+import (
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Rule checks the value of a field and describes what is wrong with it, or
+// returns "" when it is fine. Apart from Required, rules accept empty
+// values, so optional fields are only checked when they are sent.
+type Rule func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string
+
+// Required rejects missing fields, and strings made only of whitespace.
+func Required() Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set || (fd.Kind() == protoreflect.StringKind && !fd.IsList() && strings.TrimSpace(v.String()) == "") {
+			return "is required"
+		}
+		return ""
+	}
+}
+
+// Length bounds the number of characters of a string, or the number of
+// bytes of a bytes field.
+func Length(min, max int) Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set {
+			return ""
+		}
+		n, unit := utf8.RuneCountInString(v.String()), "characters"
+		if fd.Kind() == protoreflect.BytesKind {
+			n, unit = len(v.Bytes()), "bytes"
+		}
+		switch {
+		case n < min:
+			return "must be at least " + strconv.Itoa(min) + " " + unit
+		case max > 0 && n > max:
+			return "must be at most " + strconv.Itoa(max) + " " + unit
+		}
+		return ""
+	}
+}
+
+// Range bounds an integer field.
+func Range(min, max int64) Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set {
+			return ""
+		}
+		if n := v.Int(); n < min || n > max {
+			return "must be between " + strconv.FormatInt(min, 10) + " and " + strconv.FormatInt(max, 10)
+		}
+		return ""
+	}
+}
+
+// OneOf restricts a string field to the given values.
+func OneOf(values ...string) Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set {
+			return ""
+		}
+		for _, allowed := range values {
+			if v.String() == allowed {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(values, ", ")
+	}
+}
+
+// UUID requires a string field to be a UUID.
+func UUID() Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set {
+			return ""
+		}
+		if _, err := uuid.Parse(v.String()); err != nil {
+			return "must be a UUID"
+		}
+		return ""
+	}
+}
+
+// URL requires a string field to be an absolute http or https URL.
+func URL() Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set {
+			return ""
+		}
+		u, err := url.Parse(v.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an http or https URL"
+		}
+		return ""
+	}
+}
+
+// Email requires a string field to be a bare email address.
+func Email() Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set {
+			return ""
+		}
+		if addr, err := mail.ParseAddress(v.String()); err != nil || addr.Address != v.String() {
+			return "must be an email address"
+		}
+		return ""
+	}
+}
+
+// MaxItems bounds the number of elements of a repeated field.
+func MaxItems(max int) Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if set && v.List().Len() > max {
+			return "must have at most " + strconv.Itoa(max) + " items"
+		}
+		return ""
+	}
+}
+
+// Each applies rules to every element of a repeated field, reporting the
+// first element that breaks one.
+func Each(rules ...Rule) Rule {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value, set bool) string {
+		if !set {
+			return ""
+		}
+		list := v.List()
+		for i := 0; i < list.Len(); i++ {
+			for _, rule := range rules {
+				if problem := rule(element{fd}, list.Get(i), true); problem != "" {
+					return "item " + strconv.Itoa(i) + " " + problem
+				}
+			}
+		}
+		return ""
+	}
+}
+
+// element describes a single value of a repeated field to the rules
+// applied by Each.
+type element struct {
+	protoreflect.FieldDescriptor
+}
+
+func (element) IsList() bool                          { return false }
+func (element) Cardinality() protoreflect.Cardinality { return protoreflect.Optional }
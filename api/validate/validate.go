@@ -0,0 +1,71 @@
+// Package validate checks request messages against rules declared per
+// message type, reporting every offending field at once.
+package validate
+
+import (
+	"api_gateway/api/response"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Fields maps proto field names to the rules their values must satisfy.
+type Fields map[protoreflect.Name][]Rule
+
+// Set holds the field rules of each message type.
+type Set map[protoreflect.FullName]Fields
+
+// With declares the rules of msg's type and returns s, so a whole set can
+// be written as one expression. It panics on fields msg does not have, so
+// a misspelt rule fails at startup instead of never running.
+func (s Set) With(msg proto.Message, fields Fields) Set {
+	desc := msg.ProtoReflect().Descriptor()
+	for name := range fields {
+		if desc.Fields().ByName(name) == nil {
+			panic(fmt.Sprintf("validate: %s has no field %q", desc.FullName(), name))
+		}
+	}
+	s[desc.FullName()] = fields
+	return s
+}
+
+// Check returns the violations of msg, in field order. Singular message
+// fields are checked against the rules of their own type and reported
+// with a dotted path, e.g. "episode.title", named as in responses.
+func (s Set) Check(msg proto.Message) []response.FieldViolation {
+	if msg == nil {
+		return nil
+	}
+	return s.check(msg.ProtoReflect(), "", nil)
+}
+
+func (s Set) check(m protoreflect.Message, prefix string, violations []response.FieldViolation) []response.FieldViolation {
+	rules := s[m.Descriptor().FullName()]
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + fieldName(fd)
+
+		for _, rule := range rules[fd.Name()] {
+			if problem := rule(fd, m.Get(fd), m.Has(fd)); problem != "" {
+				violations = append(violations, response.FieldViolation{Field: path, Description: problem})
+				// Later rules of a field assume the earlier ones passed.
+				break
+			}
+		}
+
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() && m.Has(fd) {
+			violations = s.check(m.Get(fd).Message(), path+".", violations)
+		}
+	}
+	return violations
+}
+
+// fieldName names fd in violations the way responses name it.
+func fieldName(fd protoreflect.FieldDescriptor) string {
+	if response.MarshalOptions().UseProtoNames {
+		return string(fd.Name())
+	}
+	return fd.JSONName()
+}
@@ -2,12 +2,141 @@ package main
 
 import (
 	"api_gateway/api"
+	"api_gateway/api/health"
+	"api_gateway/api/response"
 	"api_gateway/config"
+	"api_gateway/pkg"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func main() {
 	cfg := config.Load()
 
-	router := api.NewRouter(cfg)
-	router.Run(cfg.HTTP_PORT)
+	pkg.SetRedactor(pkg.NewRedactor(cfg.REDACT_FIELDS, cfg.REDACT_MODE == "mask"))
+	response.SetMarshalOptions(protojson.MarshalOptions{
+		EmitUnpopulated: cfg.JSON_EMIT_UNPOPULATED,
+		UseProtoNames:   cfg.JSON_FIELD_NAMES != "camel",
+		UseEnumNumbers:  cfg.JSON_ENUMS == "number",
+	})
+	logger := pkg.NewLogger(os.Stdout, cfg.LOG_LEVEL)
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := pkg.InitTracing(cfg)
+	if err != nil {
+		logger.Error("cannot initialize tracing", "error", err)
+		os.Exit(1)
+	}
+
+	probe := health.NewProbe(cfg.HEALTH_CHECK_TIMEOUT, cfg.HEALTH_CACHE_TTL)
+	router := api.NewRouter(cfg, probe)
+	useTLS := cfg.TLS_CERT_FILE != ""
+	router.UseH2C = cfg.HTTP_H2C && !useTLS
+
+	server := &http.Server{
+		Addr:              cfg.HTTP_PORT,
+		Handler:           router.Handler(),
+		ReadHeaderTimeout: cfg.HTTP_READ_HEADER_TIMEOUT,
+		WriteTimeout:      cfg.HTTP_WRITE_TIMEOUT,
+		IdleTimeout:       cfg.HTTP_IDLE_TIMEOUT,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	servers := []*http.Server{server}
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if useTLS {
+		certs, err := pkg.NewCertReloader(cfg.TLS_CERT_FILE, cfg.TLS_KEY_FILE)
+		if err != nil {
+			logger.Error("cannot load TLS certificate", "error", err)
+			os.Exit(1)
+		}
+		server.TLSConfig, err = pkg.NewTLSConfig(cfg, certs)
+		if err != nil {
+			logger.Error("invalid TLS configuration", "error", err)
+			os.Exit(1)
+		}
+		go certs.Watch(watchCtx, cfg.TLS_RELOAD_INTERVAL)
+
+		if cfg.HTTP_REDIRECT_PORT != "" {
+			servers = append(servers, &http.Server{
+				Addr:              cfg.HTTP_REDIRECT_PORT,
+				Handler:           pkg.HTTPSRedirect(cfg.HTTP_PORT),
+				ReadHeaderTimeout: cfg.HTTP_READ_HEADER_TIMEOUT,
+				ReadTimeout:       cfg.HTTP_READ_TIMEOUT,
+				WriteTimeout:      cfg.HTTP_WRITE_TIMEOUT,
+				IdleTimeout:       cfg.HTTP_IDLE_TIMEOUT,
+				ErrorLog:          server.ErrorLog,
+			})
+		}
+	}
+
+	serverErr := make(chan error, len(servers))
+	go func() {
+		logger.Info("listening", "addr", cfg.HTTP_PORT, "tls", useTLS, "h2c", router.UseH2C)
+		if useTLS {
+			serverErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+	for _, redirect := range servers[1:] {
+		go func(redirect *http.Server) {
+			logger.Info("redirecting to HTTPS", "addr", redirect.Addr)
+			serverErr <- redirect.ListenAndServe()
+		}(redirect)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server stopped", "error", err)
+			os.Exit(1)
+		}
+		return
+	case sig := <-quit:
+		logger.Info("shutting down", "signal", sig.String())
+	}
+
+	probe.Drain()
+	logger.Info("readiness set to failing, waiting before draining connections", "delay", cfg.SHUTDOWN_DRAIN_DELAY.String())
+	time.Sleep(cfg.SHUTDOWN_DRAIN_DELAY)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	logger.Info("draining in-flight requests", "deadline", cfg.SHUTDOWN_TIMEOUT.String())
+	drained := true
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Error("error while draining in-flight requests", "addr", srv.Addr, "error", err)
+			drained = false
+		}
+	}
+	if drained {
+		logger.Info("in-flight requests drained")
+	}
+
+	if err := pkg.CloseConnections(); err != nil {
+		logger.Error("error while closing backend connections", "error", err)
+	} else {
+		logger.Info("backend connections closed")
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("error while flushing traces", "error", err)
+	}
+
+	logger.Info("shutdown complete")
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -16,6 +18,85 @@ type Config struct {
 	PODCAST_SERVICE_PORT        string
 	AUTHENTICATION_SERVICE_PORT string
 	SIGNING_KEY                 string
+
+	HTTP_READ_HEADER_TIMEOUT time.Duration
+	HTTP_READ_TIMEOUT        time.Duration
+	HTTP_UPLOAD_TIMEOUT      time.Duration
+	HTTP_WRITE_TIMEOUT       time.Duration
+	HTTP_IDLE_TIMEOUT        time.Duration
+	SHUTDOWN_DRAIN_DELAY     time.Duration
+	SHUTDOWN_TIMEOUT         time.Duration
+
+	TRUSTED_PROXIES []string
+
+	HTTP_H2C            bool
+	HTTP_REDIRECT_PORT  string
+	TLS_CERT_FILE       string
+	TLS_KEY_FILE        string
+	TLS_MIN_VERSION     string
+	TLS_CIPHER_SUITES   []string
+	TLS_RELOAD_INTERVAL time.Duration
+
+	HEALTH_CHECK_TIMEOUT time.Duration
+	HEALTH_CACHE_TTL     time.Duration
+
+	SERVICE_NAME        string
+	TRACE_EXPORTER      string
+	TRACE_OTLP_ENDPOINT string
+	TRACE_OTLP_INSECURE bool
+	TRACE_SAMPLE_RATIO  float64
+
+	LOG_LEVEL               string
+	LOG_SUCCESS_SAMPLE_RATE float64
+
+	REDACT_FIELDS []string
+	REDACT_MODE   string
+
+	RATE_LIMIT_READ_RPS     float64
+	RATE_LIMIT_READ_BURST   int
+	RATE_LIMIT_WRITE_RPS    float64
+	RATE_LIMIT_WRITE_BURST  int
+	RATE_LIMIT_UPLOAD_RPS   float64
+	RATE_LIMIT_UPLOAD_BURST int
+	RATE_LIMIT_IP_RPS       float64
+	RATE_LIMIT_IP_BURST     int
+	RATE_LIMIT_SHARDS       int
+
+	CORS_ALLOWED_ORIGINS   []string
+	CORS_ALLOWED_METHODS   []string
+	CORS_ALLOWED_HEADERS   []string
+	CORS_EXPOSED_HEADERS   []string
+	CORS_ALLOW_CREDENTIALS bool
+	CORS_MAX_AGE           time.Duration
+
+	COMPRESSION_MIN_SIZE     int
+	COMPRESSION_GZIP_LEVEL   int
+	COMPRESSION_BROTLI_LEVEL int
+
+	BODY_LIMIT_DEFAULT int64
+	BODY_LIMIT_UPLOAD  int64
+	BODY_CONTENT_TYPES []string
+
+	API_LEGACY_DEPRECATED_AT time.Time
+	API_LEGACY_SUNSET        time.Time
+	API_V1_DEPRECATED_AT     time.Time
+	API_V1_SUNSET            time.Time
+
+	CACHE_MAX_BYTES       int64
+	CACHE_TTL_TRENDING    time.Duration
+	CACHE_TTL_GENRES      time.Duration
+	CACHE_TTL_RECOMMENDED time.Duration
+
+	IDEMPOTENCY_TTL       time.Duration
+	IDEMPOTENCY_MAX_BYTES int64
+
+	PAGE_LIMIT_DEFAULT int
+	PAGE_LIMIT_MAX     int
+	CURSOR_SIGNING_KEY string
+
+	JSON_EMIT_UNPOPULATED bool
+	JSON_FIELD_NAMES      string
+	JSON_ENUMS            string
 }
 
 func Load() *Config {
@@ -33,6 +114,110 @@ func Load() *Config {
 	cfg.PODCAST_SERVICE_PORT = cast.ToString(coalesce("PODCAST_SERVICE_PORT", ":8084"))
 	cfg.SIGNING_KEY = cast.ToString(coalesce("SIGNING_KEY", "just do it"))
 
+	// The server only bounds reading the headers; the body is read under a
+	// per-route deadline, HTTP_UPLOAD_TIMEOUT on upload routes, which also
+	// replaces HTTP_WRITE_TIMEOUT there.
+	cfg.HTTP_READ_HEADER_TIMEOUT = cast.ToDuration(coalesce("HTTP_READ_HEADER_TIMEOUT", "10s"))
+	cfg.HTTP_READ_TIMEOUT = cast.ToDuration(coalesce("HTTP_READ_TIMEOUT", "30s"))
+	cfg.HTTP_UPLOAD_TIMEOUT = cast.ToDuration(coalesce("HTTP_UPLOAD_TIMEOUT", "15m"))
+	cfg.HTTP_WRITE_TIMEOUT = cast.ToDuration(coalesce("HTTP_WRITE_TIMEOUT", "60s"))
+	cfg.HTTP_IDLE_TIMEOUT = cast.ToDuration(coalesce("HTTP_IDLE_TIMEOUT", "120s"))
+	cfg.SHUTDOWN_DRAIN_DELAY = cast.ToDuration(coalesce("SHUTDOWN_DRAIN_DELAY", "5s"))
+	cfg.SHUTDOWN_TIMEOUT = cast.ToDuration(coalesce("SHUTDOWN_TIMEOUT", "30s"))
+
+	// Addresses or CIDRs of the proxies whose X-Forwarded-For is believed;
+	// with none, the client IP is the peer address.
+	cfg.TRUSTED_PROXIES = splitList(cast.ToString(coalesce("TRUSTED_PROXIES", "")))
+
+	// TLS is served on HTTP_PORT when a certificate is configured; h2c only
+	// applies to plain HTTP.
+	cfg.HTTP_H2C = cast.ToBool(coalesce("HTTP_H2C", false))
+	cfg.HTTP_REDIRECT_PORT = cast.ToString(coalesce("HTTP_REDIRECT_PORT", ""))
+	cfg.TLS_CERT_FILE = cast.ToString(coalesce("TLS_CERT_FILE", ""))
+	cfg.TLS_KEY_FILE = cast.ToString(coalesce("TLS_KEY_FILE", ""))
+	cfg.TLS_MIN_VERSION = cast.ToString(coalesce("TLS_MIN_VERSION", "1.2"))
+	cfg.TLS_CIPHER_SUITES = splitList(cast.ToString(coalesce("TLS_CIPHER_SUITES", "")))
+	cfg.TLS_RELOAD_INTERVAL = cast.ToDuration(coalesce("TLS_RELOAD_INTERVAL", "1m"))
+
+	cfg.HEALTH_CHECK_TIMEOUT = cast.ToDuration(coalesce("HEALTH_CHECK_TIMEOUT", "1s"))
+	cfg.HEALTH_CACHE_TTL = cast.ToDuration(coalesce("HEALTH_CACHE_TTL", "2s"))
+
+	cfg.SERVICE_NAME = cast.ToString(coalesce("SERVICE_NAME", "api_gateway"))
+	cfg.TRACE_EXPORTER = cast.ToString(coalesce("TRACE_EXPORTER", "none"))
+	cfg.TRACE_OTLP_ENDPOINT = cast.ToString(coalesce("TRACE_OTLP_ENDPOINT", "localhost:4317"))
+	cfg.TRACE_OTLP_INSECURE = cast.ToBool(coalesce("TRACE_OTLP_INSECURE", true))
+	cfg.TRACE_SAMPLE_RATIO = cast.ToFloat64(coalesce("TRACE_SAMPLE_RATIO", 1.0))
+
+	cfg.LOG_LEVEL = cast.ToString(coalesce("LOG_LEVEL", "info"))
+	cfg.LOG_SUCCESS_SAMPLE_RATE = cast.ToFloat64(coalesce("LOG_SUCCESS_SAMPLE_RATE", 1.0))
+
+	cfg.REDACT_FIELDS = splitList(cast.ToString(coalesce("REDACT_FIELDS",
+		"password,token,access_token,refresh_token,file_audio")))
+	cfg.REDACT_MODE = cast.ToString(coalesce("REDACT_MODE", "remove"))
+
+	cfg.RATE_LIMIT_READ_RPS = cast.ToFloat64(coalesce("RATE_LIMIT_READ_RPS", 20))
+	cfg.RATE_LIMIT_READ_BURST = cast.ToInt(coalesce("RATE_LIMIT_READ_BURST", 40))
+	cfg.RATE_LIMIT_WRITE_RPS = cast.ToFloat64(coalesce("RATE_LIMIT_WRITE_RPS", 2))
+	cfg.RATE_LIMIT_WRITE_BURST = cast.ToInt(coalesce("RATE_LIMIT_WRITE_BURST", 10))
+	cfg.RATE_LIMIT_UPLOAD_RPS = cast.ToFloat64(coalesce("RATE_LIMIT_UPLOAD_RPS", 0.05))
+	cfg.RATE_LIMIT_UPLOAD_BURST = cast.ToInt(coalesce("RATE_LIMIT_UPLOAD_BURST", 3))
+	// Every request also counts against the RATE_LIMIT_IP_* limit of its
+	// client IP, authenticated or not; it is shared by the users behind it.
+	cfg.RATE_LIMIT_IP_RPS = cast.ToFloat64(coalesce("RATE_LIMIT_IP_RPS", 100))
+	cfg.RATE_LIMIT_IP_BURST = cast.ToInt(coalesce("RATE_LIMIT_IP_BURST", 200))
+	cfg.RATE_LIMIT_SHARDS = cast.ToInt(coalesce("RATE_LIMIT_SHARDS", 64))
+
+	cfg.CORS_ALLOWED_ORIGINS = splitList(cast.ToString(coalesce("CORS_ALLOWED_ORIGINS", "")))
+	cfg.CORS_ALLOWED_METHODS = splitList(cast.ToString(coalesce("CORS_ALLOWED_METHODS",
+		"GET,POST,PUT,PATCH,DELETE,OPTIONS")))
+	cfg.CORS_ALLOWED_HEADERS = splitList(cast.ToString(coalesce("CORS_ALLOWED_HEADERS",
+		"Authorization,Content-Type,Accept,X-Request-Id,If-Match,If-None-Match,If-Modified-Since,Idempotency-Key")))
+	cfg.CORS_EXPOSED_HEADERS = splitList(cast.ToString(coalesce("CORS_EXPOSED_HEADERS",
+		"X-Request-Id,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After,Deprecation,Sunset,Link,ETag,Last-Modified,Age,X-Cache,Idempotent-Replayed")))
+	cfg.CORS_ALLOW_CREDENTIALS = cast.ToBool(coalesce("CORS_ALLOW_CREDENTIALS", false))
+	cfg.CORS_MAX_AGE = cast.ToDuration(coalesce("CORS_MAX_AGE", "10m"))
+
+	cfg.COMPRESSION_MIN_SIZE = cast.ToInt(coalesce("COMPRESSION_MIN_SIZE", 1024))
+	cfg.COMPRESSION_GZIP_LEVEL = cast.ToInt(coalesce("COMPRESSION_GZIP_LEVEL", 5))
+	cfg.COMPRESSION_BROTLI_LEVEL = cast.ToInt(coalesce("COMPRESSION_BROTLI_LEVEL", 4))
+
+	// JSON bodies are decoded as they arrive, the audio of an upload straight
+	// from base64; protobuf and msgpack bodies are read whole first, so an
+	// upload in those formats holds about twice its size in memory.
+	cfg.BODY_LIMIT_DEFAULT = cast.ToInt64(coalesce("BODY_LIMIT_DEFAULT", 1<<20))
+	cfg.BODY_LIMIT_UPLOAD = cast.ToInt64(coalesce("BODY_LIMIT_UPLOAD", 100<<20))
+	cfg.BODY_CONTENT_TYPES = splitList(cast.ToString(coalesce("BODY_CONTENT_TYPES", "application/json,application/x-protobuf,application/msgpack,application/x-msgpack")))
+
+	// Dates as YYYY-MM-DD; a version is deprecated once its date is set.
+	cfg.API_LEGACY_DEPRECATED_AT = cast.ToTime(coalesce("API_LEGACY_DEPRECATED_AT", ""))
+	cfg.API_LEGACY_SUNSET = cast.ToTime(coalesce("API_LEGACY_SUNSET", ""))
+	cfg.API_V1_DEPRECATED_AT = cast.ToTime(coalesce("API_V1_DEPRECATED_AT", ""))
+	cfg.API_V1_SUNSET = cast.ToTime(coalesce("API_V1_SUNSET", ""))
+
+	// A zero TTL turns caching off for the route.
+	cfg.CACHE_MAX_BYTES = cast.ToInt64(coalesce("CACHE_MAX_BYTES", 64<<20))
+	cfg.CACHE_TTL_TRENDING = cast.ToDuration(coalesce("CACHE_TTL_TRENDING", "1m"))
+	cfg.CACHE_TTL_GENRES = cast.ToDuration(coalesce("CACHE_TTL_GENRES", "5m"))
+	cfg.CACHE_TTL_RECOMMENDED = cast.ToDuration(coalesce("CACHE_TTL_RECOMMENDED", "5m"))
+
+	// How long a response is replayed for retries with the same Idempotency-Key.
+	cfg.IDEMPOTENCY_TTL = cast.ToDuration(coalesce("IDEMPOTENCY_TTL", "24h"))
+	cfg.IDEMPOTENCY_MAX_BYTES = cast.ToInt64(coalesce("IDEMPOTENCY_MAX_BYTES", 64<<20))
+
+	// Lists are served PAGE_LIMIT_DEFAULT items at a time when the client
+	// does not ask for a limit, and never more than PAGE_LIMIT_MAX.
+	cfg.PAGE_LIMIT_DEFAULT = cast.ToInt(coalesce("PAGE_LIMIT_DEFAULT", 20))
+	cfg.PAGE_LIMIT_MAX = cast.ToInt(coalesce("PAGE_LIMIT_MAX", 100))
+	// Cursors are signed with CURSOR_SIGNING_KEY, or when it is empty with a
+	// key derived from SIGNING_KEY, never with SIGNING_KEY itself.
+	cfg.CURSOR_SIGNING_KEY = cast.ToString(coalesce("CURSOR_SIGNING_KEY", ""))
+
+	// How proto messages are rendered: JSON_FIELD_NAMES is "proto" or
+	// "camel", JSON_ENUMS is "name" or "number".
+	cfg.JSON_EMIT_UNPOPULATED = cast.ToBool(coalesce("JSON_EMIT_UNPOPULATED", true))
+	cfg.JSON_FIELD_NAMES = cast.ToString(coalesce("JSON_FIELD_NAMES", "proto"))
+	cfg.JSON_ENUMS = cast.ToString(coalesce("JSON_ENUMS", "name"))
+
 	return &cfg
 }
 
@@ -43,3 +228,14 @@ func coalesce(key string, value interface{}) interface{} {
 	}
 	return value
 }
+
+// splitList splits a comma separated setting, dropping empty items.
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	pbCollaboration "api_gateway/genproto/collaborations"
+	pbPodcasts "api_gateway/genproto/podcasts"
+	pbUserManagement "api_gateway/genproto/user"
+	pbUserInteractions "api_gateway/genproto/user_interactions"
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	BackendAuthentication = "authentication"
+	BackendCollaborations = "collaborations"
+	BackendDiscovery      = "discovery"
+	BackendPodcast        = "podcast"
+	BackendUser           = "user"
+)
+
+// probeID is a well formed id that matches no record; Validate* RPCs answer
+// it without touching much more than an index.
+const probeID = "00000000-0000-0000-0000-000000000000"
+
+// Backend is a gRPC service the gateway forwards requests to.
+type Backend struct {
+	Name string
+	Conn *grpc.ClientConn
+	// Validate issues a cheap RPC to the backend. It is used to probe
+	// backends that do not implement the gRPC health protocol and is nil
+	// when the backend has no suitable RPC.
+	Validate func(ctx context.Context, conn grpc.ClientConnInterface) error
+}
+
+var validators = map[string]func(ctx context.Context, conn grpc.ClientConnInterface) error{
+	BackendCollaborations: func(ctx context.Context, conn grpc.ClientConnInterface) error {
+		_, err := pbCollaboration.NewCollaborationsClient(conn).ValidateCollaborationId(ctx, &pbCollaboration.ID{Id: probeID})
+		return err
+	},
+	BackendDiscovery: func(ctx context.Context, conn grpc.ClientConnInterface) error {
+		_, err := pbUserInteractions.NewUserInteractionsClient(conn).ValidateUserInteractionId(ctx, &pbUserInteractions.ID{Id: probeID})
+		return err
+	},
+	BackendPodcast: func(ctx context.Context, conn grpc.ClientConnInterface) error {
+		_, err := pbPodcasts.NewPodcastsClient(conn).ValidatePodcastId(ctx, &pbPodcasts.ID{Id: probeID})
+		return err
+	},
+	BackendUser: func(ctx context.Context, conn grpc.ClientConnInterface) error {
+		_, err := pbUserManagement.NewUserManagementClient(conn).ValidateUserId(ctx, &pbUserManagement.ID{Id: probeID})
+		return err
+	},
+}
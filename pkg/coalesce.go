@@ -0,0 +1,150 @@
+package pkg
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var grpcClientCoalesced = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_grpc_client_coalesced_total",
+	Help: "Backend RPCs served by joining an identical call already in flight.",
+}, []string{"backend", "service", "method"})
+
+// publicMethods answer every caller alike, so their concurrent identical
+// calls are shared across callers. Every other method is authorized by the
+// backend against the caller, so its calls are only shared by the same one.
+var publicMethods = map[string]bool{
+	"/podcasts.Podcasts/GetPodcastById":                      true,
+	"/episode_metadata.episode_metadata/GetTrendingPodcasts": true,
+	"/episode_metadata.episode_metadata/GetPodcastsByGenre":  true,
+}
+
+type coalesceKey struct{}
+
+// WithCoalescing marks ctx so identical concurrent RPCs made with it share
+// one backend call: those of the same caller, or of any caller for the
+// methods in publicMethods.
+func WithCoalescing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, coalesceKey{}, true)
+}
+
+func coalescing(ctx context.Context) bool {
+	on, _ := ctx.Value(coalesceKey{}).(bool)
+	return on
+}
+
+// inflight is a backend call shared by every caller waiting on it.
+type inflight struct {
+	// requestID is the gateway request the call is made for, which is the
+	// one backends see.
+	requestID string
+	done      chan struct{}
+	reply     proto.Message
+	err       error
+	waiters   int
+	cancel    context.CancelFunc
+}
+
+// CoalescingInterceptor lets concurrent calls with the same method and
+// request, made with a context marked by WithCoalescing, share a single call
+// to backend. Calls to methods outside publicMethods are also keyed on the
+// caller, which keeps a reply authorized for one user from reaching another;
+// the shared call carries the metadata of the
+// request that started it, which joiners log. The shared call is detached
+// from the caller that started it, so that caller going away does not fail
+// the others; it is cancelled once every caller waiting on it has gone.
+func CoalescingInterceptor(backend string) grpc.UnaryClientInterceptor {
+	var (
+		mu    sync.Mutex
+		calls = map[string]*inflight{}
+	)
+
+	return func(ctx context.Context, fullMethod string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		reqMsg, reqOK := req.(proto.Message)
+		replyMsg, replyOK := reply.(proto.Message)
+		if !coalescing(ctx) || !reqOK || !replyOK {
+			return invoker(ctx, fullMethod, req, reply, cc, opts...)
+		}
+		b, err := proto.MarshalOptions{Deterministic: true}.Marshal(reqMsg)
+		if err != nil {
+			return invoker(ctx, fullMethod, req, reply, cc, opts...)
+		}
+		caller, _ := CallerFromContext(ctx)
+		key := fullMethod + "\x00" + string(b)
+		if !publicMethods[fullMethod] {
+			key += "\x00" + caller.UserID + "\x00" + strings.Join(caller.Roles, ",")
+		}
+
+		mu.Lock()
+		call, joined := calls[key]
+		if joined {
+			call.waiters++
+		} else {
+			var callCtx context.Context
+			var cancel context.CancelFunc
+			if deadline, ok := ctx.Deadline(); ok {
+				callCtx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline)
+			} else {
+				callCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			}
+			call = &inflight{
+				requestID: caller.RequestID,
+				done:      make(chan struct{}),
+				reply:     replyMsg.ProtoReflect().New().Interface(),
+				waiters:   1,
+				cancel:    cancel,
+			}
+			calls[key] = call
+
+			go func() {
+				call.err = invoker(callCtx, fullMethod, req, call.reply, cc, opts...)
+				cancel()
+
+				mu.Lock()
+				if calls[key] == call {
+					delete(calls, key)
+				}
+				mu.Unlock()
+				close(call.done)
+			}()
+		}
+		mu.Unlock()
+
+		if joined {
+			service, method := splitMethod(fullMethod)
+			grpcClientCoalesced.WithLabelValues(backend, service, method).Inc()
+			slog.DebugContext(ctx, "joined in-flight backend call",
+				"backend", backend, "method", fullMethod, "shared_request_id", call.requestID)
+		}
+
+		select {
+		case <-call.done:
+			if call.err != nil {
+				return call.err
+			}
+			proto.Reset(replyMsg)
+			proto.Merge(replyMsg, call.reply)
+			return nil
+		case <-ctx.Done():
+			mu.Lock()
+			call.waiters--
+			if call.waiters == 0 {
+				call.cancel()
+				if calls[key] == call {
+					delete(calls, key)
+				}
+			}
+			mu.Unlock()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
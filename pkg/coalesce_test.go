@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"api_gateway/config"
+	pb "api_gateway/genproto/podcasts"
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// blockingBackend holds every call until release is closed.
+type blockingBackend struct {
+	pb.UnimplementedPodcastsServer
+
+	calls   atomic.Int32
+	arrived chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingBackend) serve() {
+	b.calls.Add(1)
+	b.arrived <- struct{}{}
+	<-b.release
+}
+
+func (b *blockingBackend) GetPodcastById(ctx context.Context, id *pb.ID) (*pb.Podcast, error) {
+	b.serve()
+	return &pb.Podcast{Id: id.GetId()}, nil
+}
+
+func (b *blockingBackend) ValidatePodcastId(ctx context.Context, id *pb.ID) (*pb.Success, error) {
+	b.serve()
+	return &pb.Success{}, nil
+}
+
+// TestCoalescingAcrossCallers checks that two users reading the same
+// podcast share one backend call, while a method authorized per caller is
+// called once for each of them.
+func TestCoalescingAcrossCallers(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &blockingBackend{arrived: make(chan struct{}, 2)}
+	server := grpc.NewServer()
+	pb.RegisterPodcastsServer(server, backend)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	client := NewPodcastsClient(&config.Config{PODCAST_SERVICE_PORT: ":" + port})
+	t.Cleanup(func() { CloseConnections() })
+
+	callers := []Caller{
+		{RequestID: "req-1", UserID: "alice", Roles: []string{"listener"}},
+		{RequestID: "req-2", UserID: "bob", Roles: []string{"podcaster"}},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		call  func(ctx context.Context) error
+		calls int32
+	}{
+		{
+			name: "GetPodcastById",
+			call: func(ctx context.Context) error {
+				_, err := client.GetPodcastById(ctx, &pb.ID{Id: "42"})
+				return err
+			},
+			calls: 1,
+		},
+		{
+			name: "ValidatePodcastId",
+			call: func(ctx context.Context) error {
+				_, err := client.ValidatePodcastId(ctx, &pb.ID{Id: "42"})
+				return err
+			},
+			calls: 2,
+		},
+	} {
+		backend.calls.Store(0)
+		backend.release = make(chan struct{})
+
+		var wg sync.WaitGroup
+		for _, caller := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx := WithCoalescing(WithCaller(context.Background(), caller))
+				if err := tc.call(ctx); err != nil {
+					t.Errorf("%s for %s: %v", tc.name, caller.UserID, err)
+				}
+			}()
+		}
+		for range tc.calls {
+			select {
+			case <-backend.arrived:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: backend called %d times, want %d", tc.name, backend.calls.Load(), tc.calls)
+			}
+		}
+		// Give a call that should have been shared time to reach the backend.
+		time.Sleep(50 * time.Millisecond)
+		close(backend.release)
+		wg.Wait()
+
+		if n := backend.calls.Load(); n != tc.calls {
+			t.Errorf("%s: backend called %d times, want %d", tc.name, n, tc.calls)
+		}
+		for len(backend.arrived) > 0 {
+			<-backend.arrived
+		}
+	}
+}
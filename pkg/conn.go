@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var (
+	connsMu  sync.Mutex
+	conns    []*grpc.ClientConn
+	backends = map[string]Backend{}
+)
+
+// dialOptions returns the options shared by every connection to backend.
+func dialOptions(backend string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		// Coalescing comes first so the interceptors after it see the one
+		// call made, with the metadata of the caller it is shared by.
+		grpc.WithChainUnaryInterceptor(
+			CoalescingInterceptor(backend),
+			MetricsInterceptor(backend),
+			LoggingInterceptor(backend),
+			MetadataInterceptor(),
+		),
+	}
+}
+
+// dial opens a connection to the named backend listening on port and
+// remembers it so it can be health checked and released on shutdown.
+// Backends without a configured port are not health checked.
+func dial(name, port string) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient("localhost"+port, dialOptions(name)...)
+	if err != nil {
+		return nil, err
+	}
+
+	connsMu.Lock()
+	defer connsMu.Unlock()
+
+	conns = append(conns, conn)
+	if _, exists := backends[name]; !exists && port != "" {
+		backends[name] = Backend{
+			Name:     name,
+			Conn:     conn,
+			Validate: validators[name],
+		}
+	}
+
+	return conn, nil
+}
+
+// Backends returns the configured backends ordered by name.
+func Backends() []Backend {
+	connsMu.Lock()
+	defer connsMu.Unlock()
+
+	list := make([]Backend, 0, len(backends))
+	for _, b := range backends {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// CloseConnections closes every backend connection opened by this package.
+func CloseConnections() error {
+	connsMu.Lock()
+	defer connsMu.Unlock()
+
+	var errs []error
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	conns = nil
+	backends = map[string]Backend{}
+
+	return errors.Join(errs...)
+}
@@ -10,19 +10,14 @@ import (
 	pbPodcasts "api_gateway/genproto/podcasts"
 	pbUserManagement "api_gateway/genproto/user"
 	pbUserInteractions "api_gateway/genproto/user_interactions"
-	"log"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"log/slog"
 )
 
-
 func NewAuthenticationClient(cfg *config.Config) pbAuthentication.AuthenticationClient{
-	conn, err := grpc.NewClient("localhost"+cfg.AUTHENTICATION_SERVICE_PORT, 
-					grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendAuthentication, cfg.AUTHENTICATION_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting authentication service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendAuthentication, "error", err)
 	}
 	a := pbAuthentication.NewAuthenticationClient(conn)
 
@@ -30,11 +25,10 @@ func NewAuthenticationClient(cfg *config.Config) pbAuthentication.Authentication
 }
 
 func NewCollaborationClient(cfg *config.Config) pbCollaboration.CollaborationsClient{
-	conn, err := grpc.NewClient("localhost"+cfg.COLLABORATIONS_SERVICE_PORT, 
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendCollaborations, cfg.COLLABORATIONS_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting collaborations service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendCollaborations, "error", err)
 	}
 	a := pbCollaboration.NewCollaborationsClient(conn)
 
@@ -42,11 +36,10 @@ func NewCollaborationClient(cfg *config.Config) pbCollaboration.CollaborationsCl
 }
 
 func NewCommentsClient(cfg *config.Config) pbComments.CommentsClient{
-	conn, err := grpc.NewClient("localhost"+cfg.COLLABORATIONS_SERVICE_PORT, 
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendCollaborations, cfg.COLLABORATIONS_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting collaborations service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendCollaborations, "error", err)
 	}
 	a := pbComments.NewCommentsClient(conn)
 
@@ -54,11 +47,10 @@ func NewCommentsClient(cfg *config.Config) pbComments.CommentsClient{
 }
 
 func NewEpisodeMetadataClient(cfg *config.Config) pbEpisodeMetadata.EpisodeMetadataClient{
-	conn, err := grpc.NewClient("localhost"+cfg.DISCOVERY_SERVICE_PORT, 
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendDiscovery, cfg.DISCOVERY_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting discovery service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendDiscovery, "error", err)
 	}
 	a := pbEpisodeMetadata.NewEpisodeMetadataClient(conn)
 
@@ -66,11 +58,10 @@ func NewEpisodeMetadataClient(cfg *config.Config) pbEpisodeMetadata.EpisodeMetad
 }
 
 func NewUserInteractionsClient(cfg *config.Config) pbUserInteractions.UserInteractionsClient{
-	conn, err := grpc.NewClient("localhost"+cfg.DISCOVERY_SERVICE_PORT, 
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendDiscovery, cfg.DISCOVERY_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting discovery service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendDiscovery, "error", err)
 	}
 	a := pbUserInteractions.NewUserInteractionsClient(conn)
 
@@ -78,11 +69,10 @@ func NewUserInteractionsClient(cfg *config.Config) pbUserInteractions.UserIntera
 }
 
 func NewEpisodesClient(cfg *config.Config) pbEpisodes.EpisodesServiceClient{
-	conn, err := grpc.NewClient("localhost"+cfg.PODCAST_SERVICE_PORT, 
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendPodcast, cfg.PODCAST_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting podcast service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendPodcast, "error", err)
 	}
 	a := pbEpisodes.NewEpisodesServiceClient(conn)
 
@@ -91,11 +81,10 @@ func NewEpisodesClient(cfg *config.Config) pbEpisodes.EpisodesServiceClient{
 
 
 func NewPodcastsClient(cfg *config.Config) pbPodcasts.PodcastsClient{
-	conn, err := grpc.NewClient("localhost"+cfg.PODCAST_SERVICE_PORT, 
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendPodcast, cfg.PODCAST_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting podcast service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendPodcast, "error", err)
 	}
 	a := pbPodcasts.NewPodcastsClient(conn)
 
@@ -103,11 +92,10 @@ func NewPodcastsClient(cfg *config.Config) pbPodcasts.PodcastsClient{
 }
 
 func NewUserManagementClient(cfg *config.Config) pbUserManagement.UserManagementClient{
-	conn, err := grpc.NewClient("localhost"+cfg.USER_SERVICE_PORT, 
-				grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := dial(BackendUser, cfg.USER_SERVICE_PORT)
 
 	if err != nil {
-		log.Println("error while connecting user service ", err)
+		slog.Error("error while connecting to backend", "backend", BackendUser, "error", err)
 	}
 	a := pbUserManagement.NewUserManagementClient(conn)
 
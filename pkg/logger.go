@@ -0,0 +1,153 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewLogger returns a JSON logger writing to w at the given level ("debug",
+// "info", "warn" or "error"). Records logged with a request context carry the
+// request id and user id of the caller.
+func NewLogger(w io.Writer, level string) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: ParseLevel(level)})
+	return slog.New(contextHandler{handler})
+}
+
+// ParseLevel maps a configured level name to a slog level, defaulting to
+// info.
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// contextHandler adds the caller found in the record's context and redacts
+// sensitive attributes before handing records to the wrapped handler.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+
+	if caller, ok := CallerFromContext(ctx); ok {
+		if caller.RequestID != "" {
+			out.AddAttrs(slog.String("request_id", caller.RequestID))
+		}
+		if caller.UserID != "" {
+			out.AddAttrs(slog.String("user_id", caller.UserID))
+		}
+	}
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return contextHandler{h.Handler.WithAttrs(redacted)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr masks attributes named like a sensitive field and strips
+// sensitive fields from proto messages logged as values.
+func redactAttr(a slog.Attr) slog.Attr {
+	if Sensitive(a.Key) {
+		return slog.String(a.Key, RedactedValue)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]any, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		if m, ok := a.Value.Any().(proto.Message); ok {
+			return slog.Any(a.Key, Redact(m))
+		}
+	}
+	return a
+}
+
+// BackendCalls collects the backends reached while serving one request, so
+// the access log can name them.
+type BackendCalls struct {
+	mu    sync.Mutex
+	names []string
+}
+
+type backendCallsKey struct{}
+
+// WithBackendCalls returns a copy of ctx in which backend calls are recorded
+// into the returned collector.
+func WithBackendCalls(ctx context.Context) (context.Context, *BackendCalls) {
+	calls := &BackendCalls{}
+	return context.WithValue(ctx, backendCallsKey{}, calls), calls
+}
+
+func (b *BackendCalls) add(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, n := range b.names {
+		if n == name {
+			return
+		}
+	}
+	b.names = append(b.names, name)
+}
+
+// Names returns the backends called so far, in call order.
+func (b *BackendCalls) Names() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.names...)
+}
+
+// LoggingInterceptor records backend in the request's BackendCalls and logs
+// every RPC to it at debug level.
+func LoggingInterceptor(backend string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if calls, ok := ctx.Value(backendCallsKey{}).(*BackendCalls); ok {
+			calls.add(backend)
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		slog.DebugContext(ctx, "backend call",
+			"backend", backend,
+			"method", method,
+			"code", status.Code(err).String(),
+			"latency_ms", time.Since(start).Milliseconds(),
+		)
+
+		return err
+	}
+}
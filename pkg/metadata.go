@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	MetadataRequestID = "x-request-id"
+	MetadataUserID    = "x-user-id"
+	MetadataUserRoles = "x-user-roles"
+	MetadataClientIP  = "x-client-ip"
+)
+
+// Caller describes the gateway request a backend call is made on behalf of.
+type Caller struct {
+	RequestID string
+	UserID    string
+	Roles     []string
+	ClientIP  string
+}
+
+type callerKey struct{}
+
+// WithCaller returns a copy of ctx carrying the given caller.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller stored in ctx by WithCaller.
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// MetadataInterceptor attaches the caller found in the call context to the
+// outgoing gRPC metadata, so backends can correlate their logs with the
+// gateway request and know who made it.
+func MetadataInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		caller, ok := CallerFromContext(ctx)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		kv := []string{}
+		if caller.RequestID != "" {
+			kv = append(kv, MetadataRequestID, caller.RequestID)
+		}
+		if caller.UserID != "" {
+			kv = append(kv, MetadataUserID, caller.UserID)
+		}
+		if len(caller.Roles) > 0 {
+			kv = append(kv, MetadataUserRoles, strings.Join(caller.Roles, ","))
+		}
+		if caller.ClientIP != "" {
+			kv = append(kv, MetadataClientIP, caller.ClientIP)
+		}
+		if len(kv) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, kv...)
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	grpcClientHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_grpc_client_handled_total",
+		Help: "Backend RPCs completed by the gateway, by backend, method and status code.",
+	}, []string{"backend", "service", "method", "code"})
+
+	grpcClientDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_grpc_client_handling_seconds",
+		Help:    "Latency of backend RPCs as seen by the gateway.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend", "service", "method"})
+
+	grpcClientResponseBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_grpc_client_response_bytes",
+		Help:    "Size of backend RPC responses.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 10),
+	}, []string{"backend", "service", "method"})
+)
+
+// MetricsInterceptor records the outcome, latency and response size of every
+// RPC made to backend. The full method name comes from the generated code,
+// so the labels stay bounded.
+func MetricsInterceptor(backend string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, fullMethod string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		service, method := splitMethod(fullMethod)
+
+		start := time.Now()
+		err := invoker(ctx, fullMethod, req, reply, cc, opts...)
+
+		grpcClientDuration.WithLabelValues(backend, service, method).Observe(time.Since(start).Seconds())
+		grpcClientHandled.WithLabelValues(backend, service, method, status.Code(err).String()).Inc()
+		if msg, ok := reply.(proto.Message); ok && err == nil {
+			grpcClientResponseBytes.WithLabelValues(backend, service, method).Observe(float64(proto.Size(msg)))
+		}
+
+		return err
+	}
+}
+
+// splitMethod splits "/package.Service/Method" into service and method.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
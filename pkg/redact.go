@@ -0,0 +1,230 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// RedactedValue replaces sensitive strings when a redactor masks instead of
+// removing.
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedFields are the proto fields never returned to clients nor
+// written to logs unless configured otherwise.
+var DefaultRedactedFields = []string{
+	"password",
+	"token",
+	"access_token",
+	"refresh_token",
+	"file_audio",
+}
+
+// Redactor strips sensitive fields, matched by proto or JSON name, from proto
+// messages.
+type Redactor struct {
+	fields map[string]struct{}
+	mask   bool
+}
+
+// NewRedactor returns a redactor for the given field names. With mask set,
+// string fields are replaced by RedactedValue instead of being cleared;
+// fields of other kinds are always cleared.
+func NewRedactor(fields []string, mask bool) *Redactor {
+	r := &Redactor{
+		fields: make(map[string]struct{}, len(fields)),
+		mask:   mask,
+	}
+	for _, f := range fields {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			r.fields[f] = struct{}{}
+		}
+	}
+	return r
+}
+
+// Sensitive reports whether a field or attribute called name is redacted.
+func (r *Redactor) Sensitive(name string) bool {
+	_, ok := r.fields[strings.ToLower(name)]
+	return ok
+}
+
+// Redact returns a copy of m without its sensitive fields, searching nested
+// messages, lists and maps. m itself is left untouched.
+func (r *Redactor) Redact(m proto.Message) proto.Message {
+	if m == nil {
+		return nil
+	}
+	clone := proto.Clone(m)
+	r.redact(clone.ProtoReflect())
+	return clone
+}
+
+func (r *Redactor) redact(m protoreflect.Message) {
+	if !m.IsValid() {
+		return
+	}
+
+	// Collect first: fields must not be cleared while ranging over them.
+	var sensitive []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if r.Sensitive(string(fd.Name())) || r.Sensitive(fd.JSONName()) {
+			sensitive = append(sensitive, fd)
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				r.redact(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				r.redact(mv.Message())
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			r.redact(v.Message())
+		}
+		return true
+	})
+
+	for _, fd := range sensitive {
+		if r.mask && fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated {
+			m.Set(fd, protoreflect.ValueOfString(RedactedValue))
+		} else {
+			m.Clear(fd)
+		}
+	}
+}
+
+// RedactJSON removes the members named like a sensitive field from the
+// objects in the JSON document b, leaving the rest as it was. Fields Redact
+// cleared are written back by protojson when it emits unpopulated fields,
+// so rendered messages go through it too. A masking redactor keeps them.
+func (r *Redactor) RedactJSON(b []byte) ([]byte, error) {
+	if r.mask {
+		return b, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+
+	// Each open container records whether it is an object, and whether a
+	// separator is due before its next member.
+	type container struct{ object, more bool }
+	var stack []container
+	afterKey := false
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		top := len(stack) - 1
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:top]
+			out.WriteByte(byte(delim))
+			continue
+		}
+
+		if top >= 0 && stack[top].object && !afterKey {
+			key := tok.(string)
+			if r.Sensitive(key) {
+				if err := skipValue(dec); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if stack[top].more {
+				out.WriteByte(',')
+			}
+			stack[top].more = true
+			enc.Encode(key)
+			out.Truncate(out.Len() - 1)
+			out.WriteByte(':')
+			afterKey = true
+			continue
+		}
+
+		if top >= 0 && !stack[top].object {
+			if stack[top].more {
+				out.WriteByte(',')
+			}
+			stack[top].more = true
+		}
+		afterKey = false
+
+		if delim, ok := tok.(json.Delim); ok {
+			stack = append(stack, container{object: delim == '{'})
+			out.WriteByte(byte(delim))
+			continue
+		}
+		if err := enc.Encode(tok); err != nil {
+			return nil, err
+		}
+		out.Truncate(out.Len() - 1)
+	}
+}
+
+// skipValue reads past the next value of dec, however deeply nested.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+var redactor atomic.Pointer[Redactor]
+
+func init() {
+	redactor.Store(NewRedactor(DefaultRedactedFields, false))
+}
+
+// SetRedactor replaces the redactor used by Redact and the logger.
+func SetRedactor(r *Redactor) {
+	redactor.Store(r)
+}
+
+// Redact strips sensitive fields from m with the configured redactor.
+func Redact(m proto.Message) proto.Message {
+	return redactor.Load().Redact(m)
+}
+
+// RedactJSON removes sensitive members from the JSON document b with the
+// configured redactor.
+func RedactJSON(b []byte) ([]byte, error) {
+	return redactor.Load().RedactJSON(b)
+}
+
+// Sensitive reports whether name is a redacted field for the configured
+// redactor.
+func Sensitive(name string) bool {
+	return redactor.Load().Sensitive(name)
+}
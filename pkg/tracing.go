@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"api_gateway/config"
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	TraceExporterNone   = "none"
+	TraceExporterStdout = "stdout"
+	TraceExporterOTLP   = "otlp"
+)
+
+// InitTracing installs the global tracer provider and the W3C trace context
+// propagator used by the HTTP middleware and the gRPC clients. The returned
+// function flushes pending spans and must be called on shutdown.
+func InitTracing(cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.TRACE_EXPORTER {
+	case TraceExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case TraceExporterStdout:
+		// stderr keeps spans out of the JSON logs written to stdout.
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case TraceExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.TRACE_OTLP_ENDPOINT)}
+		if cfg.TRACE_OTLP_INSECURE {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.TRACE_EXPORTER)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.TRACE_EXPORTER, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TRACE_SAMPLE_RATIO))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.SERVICE_NAME))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}